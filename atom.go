@@ -0,0 +1,94 @@
+package main
+
+import "strings"
+
+// Atom 1.0 documents, used by GitHub releases, YouTube channels and most static site blogs
+// Root element is <feed> with <entry> elements instead of <channel>/<item>
+type AtomFeed struct {
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+	// Language is xml:lang on the root element
+	Lang string `xml:"lang,attr"`
+	// icon is small and square, logo is wider
+	Icon      string `xml:"icon"`
+	Logo      string `xml:"logo"`
+	Generator string `xml:"generator"`
+}
+
+// Atom links are attributes, not text, and an entry can have several
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// Text constructs can be text, html or xhtml
+// xhtml is inline markup rather than escaped text, so need raw inner XML for it
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Text     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+func (text AtomText) String() string {
+	if text.Type == "xhtml" {
+		return strings.TrimSpace(text.InnerXML)
+	}
+	return strings.TrimSpace(text.Text)
+}
+
+type AtomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []AtomLink `xml:"link"`
+	Summary   AtomText   `xml:"summary"`
+	Content   AtomText   `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+// Map Atom feed into same shape as RSSFeed so scrapeFeed stores entries like RSS items
+func (atomFeed AtomFeed) toRSSFeed() RSSFeed {
+	rssFeed := RSSFeed{
+		Channel: RSSChannel{
+			Title:       strings.TrimSpace(atomFeed.Title),
+			Link:        atomAlternateLink(atomFeed.Links),
+			Description: strings.TrimSpace(atomFeed.Subtitle),
+			Language:    strings.TrimSpace(atomFeed.Lang),
+			Image:       RSSImage{URL: firstNonEmpty(atomFeed.Icon, atomFeed.Logo)},
+			Generator:   strings.TrimSpace(atomFeed.Generator),
+		},
+	}
+
+	for _, entry := range atomFeed.Entries {
+		// Prefer short summary, fall back to full content
+		description := entry.Summary.String()
+		if description == "" {
+			description = entry.Content.String()
+		}
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			Title:       strings.TrimSpace(entry.Title),
+			Link:        atomAlternateLink(entry.Links),
+			Description: description,
+			// published is optional in Atom, updated is required
+			PubDate: strings.TrimSpace(entry.Published),
+			Updated: strings.TrimSpace(entry.Updated),
+			GUID:    strings.TrimSpace(entry.ID),
+		})
+	}
+	return rssFeed
+}
+
+// rel="alternate" is the link to the page itself
+// Missing rel means alternate according to the spec
+func atomAlternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
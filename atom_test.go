@@ -0,0 +1,119 @@
+package main
+
+import "testing"
+
+func TestParseFeedAtom(t *testing.T) {
+	feed := `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="en">
+	<title>Blog</title>
+	<subtitle>Things I wrote</subtitle>
+	<link href="https://example.com/atom.xml" rel="self"/>
+	<link href="https://example.com/"/>
+	<icon>https://example.com/icon.png</icon>
+	<generator>Hugo</generator>
+	<entry>
+		<id>tag:example.com,2024:1</id>
+		<title>First</title>
+		<link href="https://example.com/1/comments" rel="replies"/>
+		<link href="https://example.com/1" rel="alternate"/>
+		<summary>Short</summary>
+		<content>Long</content>
+		<published>2024-01-02T03:04:05Z</published>
+		<updated>2024-01-03T03:04:05Z</updated>
+	</entry>
+	<entry>
+		<id>tag:example.com,2024:2</id>
+		<title>Second</title>
+		<link href="https://example.com/2"/>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Body</p></div></content>
+		<updated>2024-02-02T03:04:05Z</updated>
+	</entry>
+</feed>`
+
+	rssFeed, err := parseFeed("application/atom+xml", []byte(feed))
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+	channel := rssFeed.Channel
+	if channel.Title != "Blog" || channel.Description != "Things I wrote" {
+		t.Errorf("channel title, description = %q, %q", channel.Title, channel.Description)
+	}
+	// rel="self" comes first but isn't the page
+	if channel.Link != "https://example.com/" {
+		t.Errorf("channel link = %q, want https://example.com/", channel.Link)
+	}
+	if channel.Language != "en" || channel.imageURL() != "https://example.com/icon.png" || channel.Generator != "Hugo" {
+		t.Errorf("channel language, image, generator = %q, %q, %q", channel.Language, channel.imageURL(), channel.Generator)
+	}
+	if len(channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(channel.Item))
+	}
+
+	first := channel.Item[0]
+	want := RSSItem{
+		Title:       "First",
+		Link:        "https://example.com/1",
+		Description: "Short",
+		PubDate:     "2024-01-02T03:04:05Z",
+		Updated:     "2024-01-03T03:04:05Z",
+		GUID:        "tag:example.com,2024:1",
+	}
+	if first.Title != want.Title || first.Link != want.Link || first.Description != want.Description ||
+		first.PubDate != want.PubDate || first.Updated != want.Updated || first.GUID != want.GUID {
+		t.Errorf("first entry = %+v, want %+v", first, want)
+	}
+
+	// No summary, so xhtml content is used as it is
+	second := channel.Item[1]
+	if second.Link != "https://example.com/2" {
+		t.Errorf("second entry link = %q, want https://example.com/2", second.Link)
+	}
+	wantDescription := `<div xmlns="http://www.w3.org/1999/xhtml"><p>Body</p></div>`
+	if second.Description != wantDescription {
+		t.Errorf("second entry description = %q, want %q", second.Description, wantDescription)
+	}
+	if second.PubDate != "" || second.Updated != "2024-02-02T03:04:05Z" {
+		t.Errorf("second entry dates = %q, %q", second.PubDate, second.Updated)
+	}
+}
+
+func TestAtomAlternateLink(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []AtomLink
+		want  string
+	}{
+		{
+			name: "alternate after other rels",
+			links: []AtomLink{
+				{Href: "https://example.com/feed", Rel: "self"},
+				{Href: "https://example.com/enclosure.mp3", Rel: "enclosure"},
+				{Href: "https://example.com/post", Rel: "alternate"},
+			},
+			want: "https://example.com/post",
+		},
+		{
+			name:  "missing rel means alternate",
+			links: []AtomLink{{Href: "https://example.com/feed", Rel: "self"}, {Href: " https://example.com/post "}},
+			want:  "https://example.com/post",
+		},
+		{
+			name:  "first alternate wins",
+			links: []AtomLink{{Href: "https://example.com/en", Rel: "alternate"}, {Href: "https://example.com/de", Rel: "alternate"}},
+			want:  "https://example.com/en",
+		},
+		{
+			name:  "no alternate",
+			links: []AtomLink{{Href: "https://example.com/feed", Rel: "self"}},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := atomAlternateLink(tt.links); got != tt.want {
+				t.Errorf("atomAlternateLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
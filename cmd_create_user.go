@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+)
+
+// Same as POST /v1/users, for setting up an instance without going through the API
+// Prints the user as JSON, including its API key
+func runCreateUser(args []string) {
+	flags := flag.NewFlagSet("create-user", flag.ExitOnError)
+	name := flags.String("name", "", "name of the user")
+	cfg := parseConfig(flags, args)
+
+	if *name == "" {
+		log.Fatal("-name is required")
+	}
+
+	conn := openDatabase(cfg)
+	defer conn.Close()
+
+	checkSchema(context.Background(), conn, cfg)
+
+	db := database.New(conn)
+	user, err := db.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name:      *name,
+	})
+	if err != nil {
+		log.Fatal("Couldn't create user:", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(databaseUserToUser(user))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jakeleesh/rssagg/internal/migrate"
+)
+
+// Migrations are compiled into the binary so deploying doesn't need the sql folder or goose
+//
+//go:embed sql/schema/*.sql
+var schemaFiles embed.FS
+
+// Manage the database schema
+// migrate up applies everything pending, migrate down rolls back the latest migration,
+// migrate status lists every migration and when it was applied
+func runMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	cfg := parseConfig(flags, args)
+
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: rssagg migrate up|down|status")
+	}
+
+	migrations := loadMigrations()
+	conn := openDatabase(cfg)
+	defer conn.Close()
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	switch flags.Arg(0) {
+	case "up":
+		applied, err := migrate.Up(ctx, conn, migrations)
+		for _, migration := range applied {
+			log.Println("Applied", migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			log.Println("Database is up to date")
+		}
+	case "down":
+		migration, done, err := migrate.Down(ctx, conn, migrations)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !done {
+			log.Println("Nothing to roll back")
+			return
+		}
+		log.Println("Rolled back", migration.Name)
+	case "status":
+		statuses, err := migrate.GetStatus(ctx, conn, migrations)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MIGRATION\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "Pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%s\t%s\n", status.Name, appliedAt)
+		}
+		w.Flush()
+		// Still useful to know if database is ahead of us
+		err = migrate.Check(ctx, conn, migrations)
+		if errors.Is(err, migrate.ErrDatabaseAhead) {
+			log.Println(err)
+		}
+	default:
+		log.Fatal("Usage: rssagg migrate up|down|status")
+	}
+}
+
+func loadMigrations() []migrate.Migration {
+	schema, err := fs.Sub(schemaFiles, "sql/schema")
+	if err != nil {
+		log.Fatal(err)
+	}
+	migrations, err := migrate.Load(schema)
+	if err != nil {
+		log.Fatal("Couldn't load migrations:", err)
+	}
+	return migrations
+}
+
+// Run before anything touches the database
+// Refuses to start if database is ahead of this binary, e.g. after rolling back a deploy,
+// since queries may no longer match the schema
+// Pending migrations are applied if auto_migrate is on, otherwise also refuse to start
+func checkSchema(ctx context.Context, conn *sql.DB, cfg config) {
+	migrations := loadMigrations()
+
+	err := migrate.Check(ctx, conn, migrations)
+	if errors.Is(err, migrate.ErrPending) && cfg.AutoMigrate {
+		applied, err := migrate.Up(ctx, conn, migrations)
+		for _, migration := range applied {
+			log.Println("Applied", migration.Name)
+		}
+		if err != nil {
+			log.Fatal("Couldn't migrate database: ", err)
+		}
+		return
+	}
+	if errors.Is(err, migrate.ErrPending) {
+		log.Fatal("Refusing to start: ", err, ". Run rssagg migrate up or turn on auto_migrate")
+	}
+	if err != nil {
+		log.Fatal("Refusing to start: ", err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+)
+
+// Scraper worker loop without the API
+// Feeds are claimed with a lease, so run as many of these as needed
+func runScrape(args []string) {
+	flags := flag.NewFlagSet("scrape", flag.ExitOnError)
+	cfg := parseConfig(flags, args)
+
+	conn := openDatabase(cfg)
+	defer conn.Close()
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	checkSchema(ctx, conn, cfg)
+
+	// Blocks until we get SIGINT or SIGTERM
+	newScraper(conn, cfg).startScraping(ctx, cfg.Scraper.Concurrency, cfg.Scraper.Interval)
+}
+
+// Fetch one feed right now, whether or not it's due or disabled
+// Handy for debugging a feed, output goes to the log like the worker loop
+func runScrapeOnce(args []string) {
+	flags := flag.NewFlagSet("scrape-once", flag.ExitOnError)
+	feedIDString := flags.String("feed", "", "ID of the feed to fetch")
+	cfg := parseConfig(flags, args)
+
+	if *feedIDString == "" {
+		log.Fatal("-feed is required")
+	}
+	feedID, err := uuid.Parse(*feedIDString)
+	if err != nil {
+		log.Fatal("-feed is not a valid ID:", err)
+	}
+
+	conn := openDatabase(cfg)
+	defer conn.Close()
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	checkSchema(ctx, conn, cfg)
+
+	scr := newScraper(conn, cfg)
+	// Still take the lease so a running scraper doesn't fetch it at the same time
+	now := time.Now().UTC()
+	feed, err := scr.DB.ClaimFeed(ctx, database.ClaimFeedParams{
+		Now:            now,
+		LeaseExpiresAt: sql.NullTime{Time: now.Add(scr.leaseDuration()), Valid: true},
+		ID:             feedID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Fatal("Feed not found or being fetched by another scraper")
+	}
+	if err != nil {
+		log.Fatal("Couldn't claim feed:", err)
+	}
+
+	scr.scrapeFeed(ctx, feed)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/cors"
+	"github.com/jakeleesh/rssagg/internal/database"
+)
+
+// How long to wait for in-flight requests when shutting down
+const shutdownTimeout = 30 * time.Second
+
+// API server
+// Runs the scraper in the same process too unless -scraper=false,
+// turn it off when scrapers run separately with the scrape command
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	withScraper := flags.Bool("scraper", true, "also run the scraper in this process")
+	cfg := parseConfig(flags, args)
+
+	portString := cfg.Port
+	if portString == "" {
+		// log.Fatal will exit program immediately with Error Code 1 and message
+		log.Fatal("PORT is not found in environment, config file or flags")
+	}
+
+	conn := openDatabase(cfg)
+	scr := newScraper(conn, cfg)
+
+	// New API Config
+	// Can pass into our handlers so that they have access to database
+	apiCfg := apiConfig{
+		// Takes in database.queries
+		// Have sql.db so need to convert into a connection
+		DB:         database.New(conn),
+		Conn:       conn,
+		PostsLimit: int32(cfg.Posts.Limit),
+		Scraper:    scr,
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	checkSchema(ctx, conn, cfg)
+
+	// Hook up startScraping to main function
+	// Call before ListenAndServe() because server blocks and waits for incoming requests
+	// Call it on a new goroutine so doesn't interrupt main
+	// startScraping only returns once ctx is cancelled, closes scraperDone so we know it's finished
+	var scraperDone chan struct{}
+	if *withScraper {
+		scraperDone = make(chan struct{})
+		go func() {
+			defer close(scraperDone)
+			scr.startScraping(ctx, cfg.Scraper.Concurrency, cfg.Scraper.Interval)
+		}()
+	}
+
+	// Spin up Server
+	// New Router Object
+	router := chi.NewRouter()
+
+	// cors configuration from cors package installed
+	// Essentially telling Server to send extra HTTP Headers, tell browsers allow to use these
+	router.Use(
+		cors.Handler(
+			cors.Options{
+				// Allow send requests to http or https
+				AllowedOrigins: cfg.CORS.AllowedOrigins,
+				// Allow methods
+				AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+				// Allow send any Headers
+				AllowedHeaders:   []string{"*"},
+				ExposedHeaders:   []string{"Link"},
+				AllowCredentials: false,
+				MaxAge:           300,
+			},
+		),
+	)
+
+	// Create new Router
+	v1Router := chi.NewRouter()
+	// Hook up HTTP Handler to a specific HTTP method and path
+	// Handle /healthz path with handlerReadiness function
+	// Name healthz, kubernetes standard to see if server is live and running
+	// POST request get 200 not intention
+	// healthz endpoint should only be accessible by GET request
+	// Rather than using v1Router.handleFunc, use v1Router.Get. Scope hanlder to only fire on GET requests.
+	v1Router.Get("/healthz", handlerReadiness)
+	// Hook up error handler
+	v1Router.Get("/err", handlerErr)
+	// Hook up createUser Handler
+	// Be POST Request
+	v1Router.Post("/users", apiCfg.handlerCreateUser)
+	// Hook up GetUser Handler to GET HTTP method
+	// Same path, different method
+	// Call middlewareAuth to convert GetUser Handler into standard HTTP Handler
+	// Calling middlewareAuth to get authenticated user and then calling back the GetUser Handler
+	v1Router.Get("/users", apiCfg.middlewareAuth(apiCfg.handleGetUser))
+
+	// Creating a resouce, use POST
+	v1Router.Post("/feeds", apiCfg.middlewareAuth((apiCfg.handlerCreateFeed)))
+	v1Router.Get("/feeds", apiCfg.handlerGetFeeds)
+	// Check a URL is a feed and see what's in it without creating anything
+	v1Router.Post("/feeds/preview", apiCfg.middlewareAuth(apiCfg.handlerPreviewFeed))
+	// Anyone can look at a feed, only whoever created it can change it
+	v1Router.Get("/feeds/{feedID}", apiCfg.handlerGetFeed)
+	v1Router.Patch("/feeds/{feedID}", apiCfg.middlewareAuth(apiCfg.handlerUpdateFeed))
+	v1Router.Delete("/feeds/{feedID}", apiCfg.middlewareAuth(apiCfg.handlerDeleteFeed))
+	// Fetch now instead of waiting for the scraper, responds once done
+	v1Router.Post("/feeds/{feedID}/refresh", apiCfg.middlewareAuth(apiCfg.handlerRefreshFeed))
+
+	v1Router.Get("/posts", apiCfg.middlewareAuth(apiCfg.handlerGetPostsForUser))
+	v1Router.Get("/posts/search", apiCfg.middlewareAuth(apiCfg.handlerSearchPosts))
+	// Earlier versions of a post the publisher has since changed
+	v1Router.Get("/posts/{postID}/revisions", apiCfg.middlewareAuth(apiCfg.handlerGetPostRevisions))
+	// Read state is per user
+	v1Router.Post("/posts/{postID}/read", apiCfg.middlewareAuth(apiCfg.handlerMarkPostRead))
+	v1Router.Delete("/posts/{postID}/read", apiCfg.middlewareAuth(apiCfg.handlerMarkPostUnread))
+	v1Router.Post("/posts/read", apiCfg.middlewareAuth(apiCfg.handlerMarkPostsRead))
+	v1Router.Post("/posts/unread", apiCfg.middlewareAuth(apiCfg.handlerMarkPostsUnread))
+	v1Router.Post("/posts/mark_all_read", apiCfg.middlewareAuth(apiCfg.handlerMarkAllPostsRead))
+	v1Router.Get("/posts/unread_counts", apiCfg.middlewareAuth(apiCfg.handlerGetUnreadCounts))
+	// Saved posts, PUT again to change note and labels
+	v1Router.Get("/posts/starred", apiCfg.middlewareAuth(apiCfg.handlerGetStarredPosts))
+	v1Router.Put("/posts/{postID}/star", apiCfg.middlewareAuth(apiCfg.handlerStarPost))
+	v1Router.Delete("/posts/{postID}/star", apiCfg.middlewareAuth(apiCfg.handlerUnstarPost))
+
+	v1Router.Post("/feed_follows", apiCfg.middlewareAuth(apiCfg.handlerCreateFeedFollow))
+	v1Router.Get("/feed_follows", apiCfg.middlewareAuth(apiCfg.handlerGetFeedFollows))
+	// Authenticated
+	// Need feedFollowID and DELETE request
+	// HTTP DELETE request don't typically have body
+	// More conventional to pass ID in path
+	v1Router.Delete("/feed_follows/{feedFollowID}", apiCfg.middlewareAuth(apiCfg.handlerDeleteFeedFollow))
+
+	// Folders to organize follows into, GET /v1/posts?folder_id= reads one folder
+	v1Router.Post("/folders", apiCfg.middlewareAuth(apiCfg.handlerCreateFolder))
+	v1Router.Get("/folders", apiCfg.middlewareAuth(apiCfg.handlerGetFolders))
+	v1Router.Put("/folders/order", apiCfg.middlewareAuth(apiCfg.handlerReorderFolders))
+	v1Router.Patch("/folders/{folderID}", apiCfg.middlewareAuth(apiCfg.handlerRenameFolder))
+	v1Router.Delete("/folders/{folderID}", apiCfg.middlewareAuth(apiCfg.handlerDeleteFolder))
+	v1Router.Put("/folders/{folderID}/feed_follows/{feedFollowID}", apiCfg.middlewareAuth(apiCfg.handlerAddFeedFollowToFolder))
+	v1Router.Delete("/folders/{folderID}/feed_follows/{feedFollowID}", apiCfg.middlewareAuth(apiCfg.handlerRemoveFeedFollowFromFolder))
+
+	// Moving subscriptions to and from other readers
+	v1Router.Post("/opml", apiCfg.middlewareAuth(apiCfg.handlerImportOPML))
+	v1Router.Get("/opml", apiCfg.middlewareAuth(apiCfg.handlerExportOPML))
+
+	// Create v1Router is because going to mount
+	// Nesting v1Router under /v1 path
+	// Full path for request will be: /v1/healthz
+	// So that if make changes in future, can have 2 handlers, v1 and v2 for API. Standard practie.
+	router.Mount("/v1", v1Router)
+
+	// Connect Router to HTTP Server
+	srv := &http.Server{
+		Handler: router,
+		Addr:    ":" + portString,
+	}
+
+	log.Printf("Server starting on port %v", portString)
+	// ListenAndServe will block, just stop and starts handling HTTP Requests
+	// Run on its own goroutine so main can wait for shutdown signal
+	go func() {
+		err := srv.ListenAndServe()
+		// Shutdown makes ListenAndServe return ErrServerClosed, that's expected
+		// Anything else went wrong in process of handling requests
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			// Log and exit program
+			log.Fatal(err)
+		}
+	}()
+
+	// Block until we're told to stop
+	<-ctx.Done()
+	log.Println("Shutting down")
+
+	// Stop accepting connections and let in-flight requests finish
+	// Give up on them after shutdownTimeout
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Println("Error shutting down server:", err)
+	}
+
+	// ctx is already cancelled, scraper aborts running fetches and returns
+	if scraperDone != nil {
+		<-scraperDone
+	}
+
+	// Nothing uses database anymore
+	err = conn.Close()
+	if err != nil {
+		log.Println("Error closing database:", err)
+	}
+	log.Println("Shutdown complete")
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Everything that can be configured, shared by all commands
+// Sources, later ones win:
+//  1. Defaults in defaultConfig
+//  2. YAML file given with -config or RSSAGG_CONFIG
+//  3. Environment variables, .env is loaded into environment first
+//  4. Command line flags
+type config struct {
+	// Port the API listens on, env PORT, flag -port
+	Port string `yaml:"port"`
+	// Postgres connection string, env DB_URL, flag -db-url
+	DBURL string `yaml:"db_url"`
+	// Apply pending migrations on startup instead of refusing to start, env AUTO_MIGRATE, flag -auto-migrate
+	AutoMigrate bool          `yaml:"auto_migrate"`
+	Scraper     scraperConfig `yaml:"scraper"`
+	CORS        corsConfig    `yaml:"cors"`
+	Posts       postsConfig   `yaml:"posts"`
+}
+
+type scraperConfig struct {
+	// How many feeds are fetched at the same time, env SCRAPER_CONCURRENCY, flag -concurrency
+	Concurrency int `yaml:"concurrency"`
+	// Time between rounds of fetching, env SCRAPER_INTERVAL, flag -interval
+	Interval time.Duration `yaml:"interval"`
+	// Give up on a feed that takes longer than this to fetch, env SCRAPER_FETCH_TIMEOUT, flag -fetch-timeout
+	FetchTimeout time.Duration `yaml:"fetch_timeout"`
+	// Failures in a row before a feed is disabled, 0 never disables, env SCRAPER_MAX_FAILURES, flag -max-failures
+	MaxFailures int `yaml:"max_failures"`
+}
+
+type corsConfig struct {
+	// Origins browsers may call the API from, env CORS_ALLOWED_ORIGINS (comma separated), flag -cors-origins
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+type postsConfig struct {
+	// How many posts GET /v1/posts returns when client doesn't say, env POSTS_LIMIT, flag -posts-limit
+	Limit int `yaml:"limit"`
+}
+
+// Same values that used to be hardcoded
+func defaultConfig() config {
+	return config{
+		Scraper: scraperConfig{
+			Concurrency:  10,
+			Interval:     time.Minute,
+			FetchTimeout: 10 * time.Second,
+			MaxFailures:  10,
+		},
+		CORS: corsConfig{
+			AllowedOrigins: []string{"https://*", "http://*"},
+		},
+		Posts: postsConfig{
+			Limit: 10,
+		},
+	}
+}
+
+// Register config flags alongside the command's own flags, parse args and load config
+// Exits with a message if any source is invalid
+func parseConfig(flags *flag.FlagSet, args []string) config {
+	configPath := flags.String("config", os.Getenv("RSSAGG_CONFIG"), "path to YAML config file")
+	// Flags are parsed into their own struct, only the ones actually given override other sources
+	fromFlags := defaultConfig()
+	flags.StringVar(&fromFlags.Port, "port", "", "port the API listens on")
+	flags.StringVar(&fromFlags.DBURL, "db-url", "", "Postgres connection string")
+	flags.BoolVar(&fromFlags.AutoMigrate, "auto-migrate", false, "apply pending migrations on startup")
+	flags.IntVar(&fromFlags.Scraper.Concurrency, "concurrency", fromFlags.Scraper.Concurrency, "how many feeds to fetch at the same time")
+	flags.DurationVar(&fromFlags.Scraper.Interval, "interval", fromFlags.Scraper.Interval, "time between rounds of fetching")
+	flags.DurationVar(&fromFlags.Scraper.FetchTimeout, "fetch-timeout", fromFlags.Scraper.FetchTimeout, "timeout for fetching a single feed")
+	flags.IntVar(&fromFlags.Scraper.MaxFailures, "max-failures", fromFlags.Scraper.MaxFailures, "failures in a row before a feed is disabled, 0 never disables")
+	corsOrigins := flags.String("cors-origins", strings.Join(fromFlags.CORS.AllowedOrigins, ","), "comma separated origins allowed to call the API")
+	flags.IntVar(&fromFlags.Posts.Limit, "posts-limit", fromFlags.Posts.Limit, "how many posts GET /v1/posts returns")
+	flags.Parse(args)
+
+	cfg := defaultConfig()
+	if *configPath != "" {
+		err := cfg.loadFile(*configPath)
+		if err != nil {
+			log.Fatalf("Couldn't load config file %s: %v", *configPath, err)
+		}
+	}
+	err := cfg.loadEnv()
+	if err != nil {
+		log.Fatal("Invalid environment: ", err)
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Port = fromFlags.Port
+		case "db-url":
+			cfg.DBURL = fromFlags.DBURL
+		case "auto-migrate":
+			cfg.AutoMigrate = fromFlags.AutoMigrate
+		case "concurrency":
+			cfg.Scraper.Concurrency = fromFlags.Scraper.Concurrency
+		case "interval":
+			cfg.Scraper.Interval = fromFlags.Scraper.Interval
+		case "fetch-timeout":
+			cfg.Scraper.FetchTimeout = fromFlags.Scraper.FetchTimeout
+		case "max-failures":
+			cfg.Scraper.MaxFailures = fromFlags.Scraper.MaxFailures
+		case "cors-origins":
+			cfg.CORS.AllowedOrigins = splitList(*corsOrigins)
+		case "posts-limit":
+			cfg.Posts.Limit = fromFlags.Posts.Limit
+		}
+	})
+
+	err = cfg.validate()
+	if err != nil {
+		log.Fatal("Invalid config: ", err)
+	}
+	return cfg
+}
+
+// Keys missing from the file keep their current value
+// Unknown keys are an error so typos don't go unnoticed
+func (cfg *config) loadFile(path string) error {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(dat))
+	decoder.KnownFields(true)
+	err = decoder.Decode(cfg)
+	// Empty file is fine, nothing to override
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// Only variables that are set override
+func (cfg *config) loadEnv() error {
+	if value := os.Getenv("PORT"); value != "" {
+		cfg.Port = value
+	}
+	if value := os.Getenv("DB_URL"); value != "" {
+		cfg.DBURL = value
+	}
+	if value := os.Getenv("CORS_ALLOWED_ORIGINS"); value != "" {
+		cfg.CORS.AllowedOrigins = splitList(value)
+	}
+	if value := os.Getenv("AUTO_MIGRATE"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("AUTO_MIGRATE is not true or false: %w", err)
+		}
+		cfg.AutoMigrate = parsed
+	}
+
+	ints := []struct {
+		name string
+		dest *int
+	}{
+		{"SCRAPER_CONCURRENCY", &cfg.Scraper.Concurrency},
+		{"SCRAPER_MAX_FAILURES", &cfg.Scraper.MaxFailures},
+		{"POSTS_LIMIT", &cfg.Posts.Limit},
+	}
+	for _, env := range ints {
+		value := os.Getenv(env.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s is not a number: %w", env.name, err)
+		}
+		*env.dest = parsed
+	}
+
+	durations := []struct {
+		name string
+		dest *time.Duration
+	}{
+		{"SCRAPER_INTERVAL", &cfg.Scraper.Interval},
+		{"SCRAPER_FETCH_TIMEOUT", &cfg.Scraper.FetchTimeout},
+	}
+	for _, env := range durations {
+		value := os.Getenv(env.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s is not a duration like 30s or 5m: %w", env.name, err)
+		}
+		*env.dest = parsed
+	}
+	return nil
+}
+
+// Checked once at startup rather than failing later while running
+// Port is only needed by serve, it checks that itself
+func (cfg config) validate() error {
+	if cfg.DBURL == "" {
+		return errors.New("DB_URL is not found in environment, config file or flags")
+	}
+	if cfg.Scraper.Concurrency < 1 {
+		return errors.New("scraper concurrency must be at least 1")
+	}
+	if cfg.Scraper.Interval <= 0 {
+		return errors.New("scraper interval must be positive")
+	}
+	if cfg.Scraper.FetchTimeout <= 0 {
+		return errors.New("scraper fetch timeout must be positive")
+	}
+	if cfg.Scraper.MaxFailures < 0 {
+		return errors.New("scraper max failures can't be negative")
+	}
+	if len(cfg.CORS.AllowedOrigins) == 0 {
+		return errors.New("at least one CORS origin is needed")
+	}
+	if cfg.Posts.Limit < 1 || cfg.Posts.Limit > maxPageSize {
+		return fmt.Errorf("posts limit must be between 1 and %d", maxPageSize)
+	}
+	return nil
+}
+
+// "a, b,,c" -> [a b c]
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Environment the test runs in mustn't leak into config
+func clearConfigEnv(t *testing.T) {
+	for _, name := range []string{
+		"RSSAGG_CONFIG", "PORT", "DB_URL", "AUTO_MIGRATE", "CORS_ALLOWED_ORIGINS",
+		"SCRAPER_CONCURRENCY", "SCRAPER_INTERVAL", "SCRAPER_FETCH_TIMEOUT", "SCRAPER_MAX_FAILURES", "POSTS_LIMIT",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func writeConfigFile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "rssagg.yaml")
+	err := os.WriteFile(path, []byte(contents), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseConfigPrecedence(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, `
+port: "8000"
+db_url: postgres://file
+scraper:
+  concurrency: 4
+  interval: 2m
+  fetch_timeout: 20s
+cors:
+  allowed_origins: [https://file.example]
+posts:
+  limit: 25
+`)
+	// Environment beats the file
+	t.Setenv("DB_URL", "postgres://env")
+	t.Setenv("SCRAPER_CONCURRENCY", "6")
+	t.Setenv("SCRAPER_INTERVAL", "3m")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example, https://b.example")
+
+	// Flags beat both
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	cfg := parseConfig(flags, []string{"-config", path, "-concurrency", "8", "-db-url", "postgres://flag"})
+
+	want := defaultConfig()
+	// Only in the file
+	want.Port = "8000"
+	want.Scraper.FetchTimeout = 20 * time.Second
+	want.Posts.Limit = 25
+	// File and environment
+	want.Scraper.Interval = 3 * time.Minute
+	want.CORS.AllowedOrigins = []string{"https://a.example", "https://b.example"}
+	// All three
+	want.DBURL = "postgres://flag"
+	want.Scraper.Concurrency = 8
+
+	if cfg.Port != want.Port || cfg.DBURL != want.DBURL || cfg.AutoMigrate != want.AutoMigrate ||
+		cfg.Scraper != want.Scraper || cfg.Posts != want.Posts ||
+		!slices.Equal(cfg.CORS.AllowedOrigins, want.CORS.AllowedOrigins) {
+		t.Errorf("parseConfig() = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestParseConfigFlagDefaultsDontOverride(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("DB_URL", "postgres://env")
+	t.Setenv("SCRAPER_CONCURRENCY", "3")
+	t.Setenv("RSSAGG_CONFIG", writeConfigFile(t, "posts:\n  limit: 50\n"))
+
+	// Flags that weren't given keep their defaults out of the way
+	flags := flag.NewFlagSet("scrape", flag.ContinueOnError)
+	cfg := parseConfig(flags, nil)
+	if cfg.Scraper.Concurrency != 3 {
+		t.Errorf("concurrency = %d, want 3 from environment", cfg.Scraper.Concurrency)
+	}
+	if cfg.Posts.Limit != 50 {
+		t.Errorf("posts limit = %d, want 50 from RSSAGG_CONFIG file", cfg.Posts.Limit)
+	}
+}
+
+func TestLoadFileRejectsUnknownKeys(t *testing.T) {
+	cfg := defaultConfig()
+	err := cfg.loadFile(writeConfigFile(t, "scraper:\n  concurency: 4\n"))
+	if err == nil {
+		t.Fatal("loadFile() accepted a misspelled key")
+	}
+
+	// Empty file changes nothing
+	cfg = defaultConfig()
+	err = cfg.loadFile(writeConfigFile(t, ""))
+	if err != nil || cfg.Scraper != defaultConfig().Scraper {
+		t.Errorf("loadFile() of empty file = %+v, %v", cfg, err)
+	}
+}
+
+func TestLoadEnvRejectsInvalidValues(t *testing.T) {
+	for name, value := range map[string]string{
+		"AUTO_MIGRATE":          "sometimes",
+		"SCRAPER_CONCURRENCY":   "ten",
+		"SCRAPER_FETCH_TIMEOUT": "10",
+	} {
+		t.Run(name, func(t *testing.T) {
+			clearConfigEnv(t)
+			t.Setenv(name, value)
+			cfg := defaultConfig()
+			if err := cfg.loadEnv(); err == nil {
+				t.Errorf("loadEnv() accepted %s=%q", name, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Which date posts.published_at came from
+// Stored on the post so we know which ones got a guessed date
+const (
+	publishedAtSourcePublished = "published"
+	publishedAtSourceDCDate    = "dc_date"
+	publishedAtSourceUpdated   = "updated"
+	publishedAtSourceFetched   = "fetched"
+)
+
+// Layouts seen in the wild, most common first
+// Day is "2" rather than "02" so single digit days parse too
+var dateLayouts = []string{
+	// RSS 2.0, RFC 822 with and without numeric zones
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04 MST",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"Mon, 2 January 2006 15:04:05 MST",
+	"Monday, 2-Jan-06 15:04:05 MST",
+	// Full day name, usually from feeds built by hand
+	"Monday, 2 Jan 2006 15:04:05 -0700",
+	"Monday, 2 Jan 2006 15:04:05 MST",
+	time.ANSIC,
+	// ISO 8601 / RFC 3339, used by Atom, JSON Feed and dc:date
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// Named zones Go can't resolve on its own
+// time.Parse gives unknown abbreviations a zero offset, which would shift posts by hours
+// Ambiguous ones like IST aren't here, dates using them are rejected instead
+var zoneOffsets = map[string]int{
+	"EST":  -5 * 60 * 60,
+	"EDT":  -4 * 60 * 60,
+	"CST":  -6 * 60 * 60,
+	"CDT":  -5 * 60 * 60,
+	"MST":  -7 * 60 * 60,
+	"MDT":  -6 * 60 * 60,
+	"PST":  -8 * 60 * 60,
+	"PDT":  -7 * 60 * 60,
+	"AKST": -9 * 60 * 60,
+	"AKDT": -8 * 60 * 60,
+	"HST":  -10 * 60 * 60,
+	"WET":  0,
+	"WEST": 1 * 60 * 60,
+	"BST":  1 * 60 * 60,
+	"CET":  1 * 60 * 60,
+	"CEST": 2 * 60 * 60,
+	"EET":  2 * 60 * 60,
+	"EEST": 3 * 60 * 60,
+	"MSK":  3 * 60 * 60,
+	"JST":  9 * 60 * 60,
+	"KST":  9 * 60 * 60,
+	"AEST": 10 * 60 * 60,
+	"AEDT": 11 * 60 * 60,
+	"NZST": 12 * 60 * 60,
+	"NZDT": 13 * 60 * 60,
+}
+
+// Zones that really are a zero offset, blank is a numeric +0000
+var utcZoneNames = map[string]bool{"": true, "UTC": true, "GMT": true, "Z": true}
+
+// Try each layout until one works
+// Returned time is always UTC
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, errors.New("empty date")
+	}
+	// RFC 822 allows "UT", Go only knows zone names of three letters or more
+	if strings.HasSuffix(value, " UT") {
+		value += "C"
+	}
+
+	for _, layout := range dateLayouts {
+		parsed, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+
+		name, offset := parsed.Zone()
+		if offset == 0 && !utcZoneNames[name] {
+			zoneOffset, ok := zoneOffsets[name]
+			if !ok {
+				// Reading it as UTC would silently be off by hours
+				return time.Time{}, errors.New("unknown time zone " + name + " in date: " + value)
+			}
+			// Same wall clock, correct offset
+			parsed = time.Date(
+				parsed.Year(), parsed.Month(), parsed.Day(),
+				parsed.Hour(), parsed.Minute(), parsed.Second(), parsed.Nanosecond(),
+				time.FixedZone(name, zoneOffset),
+			)
+		}
+		return parsed.UTC(), nil
+	}
+	return time.Time{}, errors.New("unrecognized date format: " + value)
+}
+
+// Work out when an item was published
+// Tries pubDate, then dc:date, then updated, and finally uses fetch time
+// Second return value says which one was used
+func itemPublishedAt(item RSSItem, fetchedAt time.Time) (time.Time, string) {
+	candidates := []struct {
+		value  string
+		source string
+	}{
+		{item.PubDate, publishedAtSourcePublished},
+		{item.DCDate, publishedAtSourceDCDate},
+		{item.Updated, publishedAtSourceUpdated},
+	}
+	for _, candidate := range candidates {
+		pubAt, err := parseDate(candidate.value)
+		if err == nil {
+			return pubAt, candidate.source
+		}
+	}
+	return fetchedAt, publishedAtSourceFetched
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "RFC 1123 with numeric zone",
+			value: "Mon, 02 Jan 2006 15:04:05 -0700",
+			want:  time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "single digit day",
+			value: "Mon, 2 Jan 2006 15:04:05 +0000",
+			want:  time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "GMT",
+			value: "Mon, 02 Jan 2006 15:04:05 GMT",
+			want:  time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "UT",
+			value: "Mon, 02 Jan 2006 04:00:00 UT",
+			want:  time.Date(2006, 1, 2, 4, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "full day name",
+			value: "Monday, 02 Jan 2006 15:04:05 GMT",
+			want:  time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "US named zone",
+			value: "Mon, 02 Jan 2006 15:04:05 EST",
+			want:  time.Date(2006, 1, 2, 20, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "European named zone",
+			value: "Sun, 02 Jul 2006 15:04:05 CEST",
+			want:  time.Date(2006, 7, 2, 13, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "without seconds",
+			value: "02 Jan 2006 15:04 -0700",
+			want:  time.Date(2006, 1, 2, 22, 4, 0, 0, time.UTC),
+		},
+		{
+			name:  "RFC 3339",
+			value: "2006-01-02T15:04:05+02:00",
+			want:  time.Date(2006, 1, 2, 13, 4, 5, 0, time.UTC),
+		},
+		{
+			name:  "RFC 3339 fractional seconds",
+			value: "2006-01-02T15:04:05.123Z",
+			want:  time.Date(2006, 1, 2, 15, 4, 5, 123000000, time.UTC),
+		},
+		{
+			name:  "date only",
+			value: "2006-01-02",
+			want:  time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "surrounding whitespace",
+			value: "  2006-01-02T15:04:05Z\n",
+			want:  time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		},
+		{
+			name:    "unknown named zone",
+			value:   "Mon, 02 Jan 2006 15:04:05 XST",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			value:   "",
+			wantErr: true,
+		},
+		{
+			name:    "not a date",
+			value:   "yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDate(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseDate(%q) = %v, want error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDate(%q) returned error: %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("parseDate(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestItemPublishedAt(t *testing.T) {
+	fetchedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		item       RSSItem
+		want       time.Time
+		wantSource string
+	}{
+		{
+			name:       "pubDate",
+			item:       RSSItem{PubDate: "Mon, 02 Jan 2006 15:04:05 GMT", Updated: "2007-01-02T00:00:00Z"},
+			want:       time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			wantSource: publishedAtSourcePublished,
+		},
+		{
+			name:       "dc:date when pubDate is unreadable",
+			item:       RSSItem{PubDate: "soon", DCDate: "2006-01-02T15:04:05Z"},
+			want:       time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			wantSource: publishedAtSourceDCDate,
+		},
+		{
+			name:       "updated",
+			item:       RSSItem{Updated: "2006-01-02T15:04:05Z"},
+			want:       time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			wantSource: publishedAtSourceUpdated,
+		},
+		{
+			name:       "fetch time",
+			item:       RSSItem{PubDate: "soon"},
+			want:       fetchedAt,
+			wantSource: publishedAtSourceFetched,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, source := itemPublishedAt(tt.item, fetchedAt)
+			if !got.Equal(tt.want) || source != tt.wantSource {
+				t.Errorf("itemPublishedAt() = %v, %q, want %v, %q", got, source, tt.want, tt.wantSource)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+	"github.com/jakeleesh/rssagg/internal/migrate"
+)
+
+// Tests that need Postgres run against TEST_DB_URL and are skipped without it
+// Migrations are applied first, so point it at a database that's only used for tests
+func testAPIConfig(t *testing.T) *apiConfig {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL not set")
+	}
+
+	conn, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	_, err = migrate.Up(context.Background(), conn, loadMigrations())
+	if err != nil {
+		t.Fatal("couldn't migrate test database:", err)
+	}
+
+	db := database.New(conn)
+	return &apiConfig{
+		DB:         db,
+		Conn:       conn,
+		PostsLimit: 10,
+		Scraper:    &scraper{DB: db, conn: conn, fetchTimeout: 5 * time.Second},
+	}
+}
+
+// User that's deleted again when the test is done, everything they own goes with them
+func testUser(t *testing.T, apiCfg *apiConfig) database.User {
+	t.Helper()
+	user, err := apiCfg.DB.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name:      t.Name(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		apiCfg.Conn.Exec("DELETE FROM users WHERE id = $1", user.ID)
+	})
+	return user
+}
+
+// Request with chi URL parameters set, as the router would
+func withURLParams(r *http.Request, params map[string]string) *http.Request {
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// Feed URLs come from users, so fetching one mustn't reach the server itself or the network it sits on
+// e.g. http://169.254.169.254/ for cloud credentials, or localhost ports
+var errAddressNotAllowed = errors.New("address not allowed")
+
+// Shared carrier-grade NAT range, some clouds put metadata services here
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Transport for everything fetched on a user's behalf: feeds, web pages and feeds found on them
+// Address is checked after DNS resolution and on every connection, so redirects are covered too
+// No proxy, it would be the proxy's address that got checked
+var feedTransport = &http.Transport{
+	DialContext: (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   allowPublicAddress,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+// Dialer Control hook, address is the resolved IP and port about to be connected to
+func allowPublicAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errAddressNotAllowed, address)
+	}
+	if !isPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errAddressNotAllowed, addrPort.Addr())
+	}
+	return nil
+}
+
+// Loopback, private, link-local, unspecified and multicast addresses aren't public
+func isPublicAddress(addr netip.Addr) bool {
+	// ::ffff:127.0.0.1 is still loopback
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"html"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feed found on a web page, for users who paste a blog's homepage rather than its feed
+type FeedCandidate struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Media type the page advertised, blank for feeds found by trying common paths
+	Type string `json:"type"`
+}
+
+// Types in <link rel="alternate"> that point at feeds we can read
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/rdf+xml":   true,
+	"application/feed+json": true,
+}
+
+// Where blogs usually keep their feed, tried when page doesn't link to one
+var commonFeedPaths = []string{"/feed", "/rss", "/feed.xml", "/rss.xml", "/atom.xml", "/index.xml"}
+
+// Pages aren't XML, so pick tags and attributes out with regular expressions
+var (
+	linkTagRegexp   = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	attributeRegexp = regexp.MustCompile(`(?s)([a-zA-Z_:-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+)
+
+// Look for feeds on a web page urlToFeed already fetched, page is its body
+// base is where the page ended up after redirects, relative links are relative to it
+// Feeds the page links to come first, common paths are only tried if it doesn't link to any
+func discoverFeeds(ctx context.Context, base *url.URL, page []byte, timeout time.Duration) []FeedCandidate {
+	candidates := feedLinks(base, string(page))
+	if len(candidates) > 0 {
+		return candidates
+	}
+	return probeCommonFeedPaths(ctx, base, timeout)
+}
+
+// <link rel="alternate" type="application/rss+xml" href="..."> tags in page
+func feedLinks(base *url.URL, page string) []FeedCandidate {
+	candidates := []FeedCandidate{}
+	seen := map[string]bool{}
+	for _, tag := range linkTagRegexp.FindAllString(page, -1) {
+		attrs := map[string]string{}
+		for _, match := range attributeRegexp.FindAllStringSubmatch(tag, -1) {
+			value := strings.Trim(match[2], `"'`)
+			attrs[strings.ToLower(match[1])] = strings.TrimSpace(html.UnescapeString(value))
+		}
+
+		// rel can have several values, e.g. "alternate home"
+		if !containsFold(strings.Fields(attrs["rel"]), "alternate") {
+			continue
+		}
+		mediaType, _, err := mime.ParseMediaType(attrs["type"])
+		if err != nil || !feedLinkTypes[mediaType] {
+			continue
+		}
+		href, err := base.Parse(attrs["href"])
+		if err != nil || attrs["href"] == "" || !isHTTPURL(href.String()) {
+			continue
+		}
+		if seen[href.String()] {
+			continue
+		}
+		seen[href.String()] = true
+		candidates = append(candidates, FeedCandidate{
+			URL:   href.String(),
+			Title: attrs["title"],
+			Type:  mediaType,
+		})
+	}
+	return candidates
+}
+
+// Try commonFeedPaths on the page's host, all at once
+// Only paths that actually serve a feed are returned, in commonFeedPaths order
+func probeCommonFeedPaths(ctx context.Context, base *url.URL, timeout time.Duration) []FeedCandidate {
+	found := make([]*FeedCandidate, len(commonFeedPaths))
+	wg := &sync.WaitGroup{}
+	for i, path := range commonFeedPaths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			feedURL := base.ResolveReference(&url.URL{Path: path}).String()
+			feedFetch, err := urlToFeed(ctx, feedURL, "", "", timeout)
+			if err != nil {
+				return
+			}
+			found[i] = &FeedCandidate{
+				URL:   feedURL,
+				Title: strings.TrimSpace(feedFetch.RSSFeed.Channel.Title),
+			}
+		}()
+	}
+	wg.Wait()
+
+	candidates := []FeedCandidate{}
+	seen := map[string]bool{}
+	for _, candidate := range found {
+		if candidate == nil {
+			continue
+		}
+		// /feed and /rss often serve the same feed, one of them is enough
+		key := firstNonEmpty(candidate.Title, candidate.URL)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, *candidate)
+	}
+	return candidates
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
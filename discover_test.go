@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFeedLinks(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html><head>
+	<link rel="stylesheet" href="/style.css">
+	<link rel="alternate" type="application/rss+xml" title="Posts" href="/feed.xml">
+	<LINK REL="Alternate home" TYPE="application/atom+xml; charset=utf-8" HREF='atom.xml' title="Atom">
+	<link rel=alternate type=application/feed+json href=../feed.json>
+	<link rel="alternate" type="application/rss+xml" href="//cdn.example.com/comments.rss" title="Comments &amp; replies">
+	<link rel="alternate" type="application/rss+xml" href="https://blog.example.com/feed.xml" title="Posts again">
+	<link rel="alternate" hreflang="de" href="/de/">
+	<link rel="alternate" type="text/html" href="/print">
+	<link rel="alternate" type="application/rss+xml" href="">
+	<link rel="alternate" type="application/rss+xml" href="javascript:alert(1)">
+</head><body></body></html>`
+	// Page after redirects, relative links resolve against it
+	base, err := url.Parse("https://blog.example.com/2024/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := feedLinks(base, page)
+	want := []FeedCandidate{
+		{URL: "https://blog.example.com/feed.xml", Title: "Posts", Type: "application/rss+xml"},
+		{URL: "https://blog.example.com/2024/atom.xml", Title: "Atom", Type: "application/atom+xml"},
+		{URL: "https://blog.example.com/feed.json", Type: "application/feed+json"},
+		{URL: "https://cdn.example.com/comments.rss", Title: "Comments & replies", Type: "application/rss+xml"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("feedLinks() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDiscoverFeedsReadsFetchedPage(t *testing.T) {
+	// Host doesn't resolve, so anything other than reading the page we already have would come back empty
+	base, err := url.Parse("https://blog.invalid/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := []byte(`<link rel="alternate" type="application/atom+xml" href="/atom.xml">`)
+	got := discoverFeeds(context.Background(), base, page, time.Second)
+	want := []FeedCandidate{{URL: "https://blog.invalid/atom.xml", Type: "application/atom+xml"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("discoverFeeds() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFeedLinksNone(t *testing.T) {
+	base, err := url.Parse("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := feedLinks(base, `<html><head><title>No feeds</title></head></html>`)
+	if len(got) != 0 {
+		t.Errorf("feedLinks() = %+v, want none", got)
+	}
+}
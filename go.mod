@@ -13,3 +13,5 @@ require (
 )
 
 require github.com/lib/pq v1.10.9
+
+require gopkg.in/yaml.v3 v3.0.1
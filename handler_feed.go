@@ -1,23 +1,36 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 	"github.com/jakeleesh/rssagg/internal/database"
 )
 
+// How many items POST /v1/feeds/preview shows
+const feedPreviewItems = 10
+
 // Authenticated endpoint so accept user directly
 // Know who's creating the feed by the time get to this function
+// URL is fetched first so only actual feeds get stored
+// Can be a web page that links to its feed, the feed's own URL is what's stored
 func (apiCfg *apiConfig) handlerCreateFeed(w http.ResponseWriter, r *http.Request, user database.User) {
 	// Want the user that's creating the new feed to just send a name and URL
 	// and we'll go creating entire feed object
+	// Name is optional, feed's own title is used when it's left out
+	// Follow true also follows the feed, saves a call to /v1/feed_follows
 	type parameters struct {
-		Name string `json:"name"`
-		URL  string `json:"url"`
+		Name   string `json:"name"`
+		URL    string `json:"url"`
+		Follow bool   `json:"follow"`
 	}
 	decoder := json.NewDecoder(r.Body)
 
@@ -28,22 +41,143 @@ func (apiCfg *apiConfig) handlerCreateFeed(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	feed, err := apiCfg.DB.CreateFeed(r.Context(), database.CreateFeedParams{
+	// Already have it, no need to fetch anything
+	_, err = apiCfg.DB.GetFeedByURL(r.Context(), strings.TrimSpace(params.URL))
+	if err == nil {
+		respondWithError(w, 409, "Feed with that URL already exists")
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't look up feed: %v", err))
+		return
+	}
+
+	feedURL, rssFeed, ok := apiCfg.fetchFeedToCheck(w, r, strings.TrimSpace(params.URL))
+	if !ok {
+		return
+	}
+	channel := rssFeed.Channel
+
+	// Feed and follow are created together or not at all
+	tx, err := apiCfg.Conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, fmt.Sprintf("Couldn't start transaction: %v", err))
+		return
+	}
+	// No-op once committed
+	defer tx.Rollback()
+	qtx := apiCfg.DB.WithTx(tx)
+
+	feed, err := qtx.CreateFeed(r.Context(), database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
-		Name:      params.Name,
-		Url:       params.URL,
+		// Feeds need a name, URL will do if feed has no title either
+		Name: firstNonEmpty(strings.TrimSpace(params.Name), strings.TrimSpace(channel.Title), feedURL),
+		Url:  feedURL,
 		// ID exists on user object
-		UserID: user.ID,
+		UserID:      user.ID,
+		Description: nullString(strings.TrimSpace(channel.Description)),
+		SiteUrl:     nullString(strings.TrimSpace(channel.Link)),
+		Language:    nullString(strings.TrimSpace(channel.Language)),
+		ImageUrl:    nullString(channel.imageURL()),
+		Generator:   nullString(strings.TrimSpace(channel.Generator)),
 	})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Feed with that URL already exists")
+		return
+	}
 	if err != nil {
-		respondWithError(w, 400, fmt.Sprintf("Couldn't create user: %v", err))
+		respondWithError(w, 400, fmt.Sprintf("Couldn't create feed: %v", err))
 		return
 	}
 
+	// Same fields as any other feed, plus the follow when one was made
+	type response struct {
+		Feed
+		FeedFollow *FeedFollow `json:"feed_follow,omitempty"`
+	}
 	// Don't want to just directly return struct
-	respondWithJSON(w, 201, databaseFeedToFeed(feed))
+	resp := response{Feed: databaseFeedToFeed(feed)}
+
+	if params.Follow {
+		dbFeedFollow, _, err := followFeed(r.Context(), qtx, user.ID, feed.ID)
+		if err != nil {
+			respondWithError(w, 400, fmt.Sprintf("Couldn't create feed follow: %v", err))
+			return
+		}
+		feedFollow := databaseFeedFollowToFeedFollow(dbFeedFollow)
+		resp.FeedFollow = &feedFollow
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		respondWithError(w, 500, fmt.Sprintf("Couldn't create feed: %v", err))
+		return
+	}
+	respondWithJSON(w, 201, resp)
+}
+
+// Fetch a URL and show what's in its feed, nothing is stored
+// Body {"url": "..."}, lets clients check a URL before subscribing
+// Web pages are searched for feeds the same way as when creating one
+func (apiCfg *apiConfig) handlerPreviewFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		URL string `json:"url"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	feedURL, rssFeed, ok := apiCfg.fetchFeedToCheck(w, r, strings.TrimSpace(params.URL))
+	if !ok {
+		return
+	}
+	respondWithJSON(w, 200, rssFeedToFeedPreview(feedURL, rssFeed, feedPreviewItems))
+}
+
+// Fetch and parse feedURL on behalf of a client
+// A web page is searched for feeds instead, one found is used, several are sent back for client to pick from
+// Returns URL of the feed that was read, which differs from feedURL when it was found on a page
+// Writes the error response and returns false if there isn't a feed we can read
+func (apiCfg *apiConfig) fetchFeedToCheck(w http.ResponseWriter, r *http.Request, feedURL string) (string, RSSFeed, bool) {
+	if !isHTTPURL(feedURL) {
+		respondWithError(w, 400, "URL must be an http or https URL")
+		return "", RSSFeed{}, false
+	}
+
+	feedFetch, err := urlToFeed(r.Context(), feedURL, "", "", apiCfg.Scraper.fetchTimeout)
+	if errors.Is(err, errNotAFeed) {
+		// Page is already downloaded, look through it rather than fetching it again
+		candidates := discoverFeeds(r.Context(), feedFetch.PageURL, feedFetch.Page, apiCfg.Scraper.fetchTimeout)
+		if len(candidates) == 0 {
+			respondWithError(w, 422, fmt.Sprintf("Couldn't use URL: %v, and no feeds found on the page", err))
+			return "", RSSFeed{}, false
+		}
+		if len(candidates) > 1 {
+			// Same shape as other errors, with the feeds to pick from
+			type response struct {
+				Error      string          `json:"Error"`
+				Candidates []FeedCandidate `json:"candidates"`
+			}
+			respondWithJSON(w, 422, response{
+				Error:      "URL is a web page with several feeds, send again with the url of one of them",
+				Candidates: candidates,
+			})
+			return "", RSSFeed{}, false
+		}
+		feedURL = candidates[0].URL
+		feedFetch, err = urlToFeed(r.Context(), feedURL, "", "", apiCfg.Scraper.fetchTimeout)
+	}
+	// Not a feed, or couldn't fetch it at all
+	if err != nil {
+		respondWithError(w, 422, fmt.Sprintf("Couldn't use URL: %v", err))
+		return "", RSSFeed{}, false
+	}
+	return feedURL, feedFetch.RSSFeed, true
 }
 
 // Not authenticated so don't have to pass in a User
@@ -58,3 +192,171 @@ func (apiCfg *apiConfig) handlerGetFeeds(w http.ResponseWriter, r *http.Request)
 
 	respondWithJSON(w, 201, databaseFeedstoFeeds(feeds))
 }
+
+func (apiCfg *apiConfig) handlerGetFeed(w http.ResponseWriter, r *http.Request) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feedID"))
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't parse feed id: %v", err))
+		return
+	}
+
+	feed, err := apiCfg.DB.GetFeed(r.Context(), feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Feed not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get feed: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, databaseFeedToFeed(feed))
+}
+
+// Change name or URL, body {"name": "...", "url": "..."}, fields left out stay the same
+// New URL is fetched from scratch, and a feed disabled for failing gets another chance
+func (apiCfg *apiConfig) handlerUpdateFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := apiCfg.ownedFeed(w, r, user)
+	if !ok {
+		return
+	}
+
+	type parameters struct {
+		Name *string `json:"name"`
+		URL  *string `json:"url"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	name := feed.Name
+	if params.Name != nil {
+		name = strings.TrimSpace(*params.Name)
+		if name == "" {
+			respondWithError(w, 400, "Name can't be blank")
+			return
+		}
+	}
+	feedURL := feed.Url
+	if params.URL != nil {
+		feedURL = strings.TrimSpace(*params.URL)
+		if !isHTTPURL(feedURL) {
+			respondWithError(w, 400, "URL must be an http or https URL")
+			return
+		}
+	}
+
+	updated, err := apiCfg.DB.UpdateFeed(r.Context(), database.UpdateFeedParams{
+		Name:      name,
+		Url:       feedURL,
+		UpdatedAt: time.Now().UTC(),
+		ID:        feed.ID,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "Another feed already has that URL")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't update feed: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, databaseFeedToFeed(updated))
+}
+
+// Posts and everyone's follows of the feed go with it, stars stay with a copy of their post
+func (apiCfg *apiConfig) handlerDeleteFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := apiCfg.ownedFeed(w, r, user)
+	if !ok {
+		return
+	}
+
+	err := apiCfg.DB.DeleteFeed(r.Context(), feed.ID)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't delete feed: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, struct{}{})
+}
+
+// Fetch the feed right now, whether or not it's due or disabled
+// Responds with the feed after fetching and what the fetch did
+func (apiCfg *apiConfig) handlerRefreshFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := apiCfg.ownedFeed(w, r, user)
+	if !ok {
+		return
+	}
+
+	// Same lease the scraper takes, so it doesn't fetch the feed at the same time
+	now := time.Now().UTC()
+	feed, err := apiCfg.DB.ClaimFeed(r.Context(), database.ClaimFeedParams{
+		Now:            now,
+		LeaseExpiresAt: sql.NullTime{Time: now.Add(apiCfg.Scraper.leaseDuration()), Valid: true},
+		ID:             feed.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 409, "Feed is being fetched right now, try again shortly")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't claim feed: %v", err))
+		return
+	}
+
+	outcome := apiCfg.Scraper.scrapeFeed(r.Context(), feed)
+
+	// Fetch status and URL may have changed, feed may even have been merged into another
+	feedID := feed.ID
+	if outcome.MovedTo != "" {
+		moved, err := apiCfg.DB.GetFeedByURL(r.Context(), outcome.MovedTo)
+		if err == nil {
+			feedID = moved.ID
+		}
+	}
+	feed, err = apiCfg.DB.GetFeed(r.Context(), feedID)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get feed: %v", err))
+		return
+	}
+
+	type response struct {
+		Feed  Feed         `json:"feed"`
+		Fetch fetchOutcome `json:"fetch"`
+	}
+	respondWithJSON(w, 200, response{
+		Feed:  databaseFeedToFeed(feed),
+		Fetch: outcome,
+	})
+}
+
+// Feed from the {feedID} in the path, if it belongs to user
+// Writes the error response and returns false otherwise
+func (apiCfg *apiConfig) ownedFeed(w http.ResponseWriter, r *http.Request, user database.User) (database.Feed, bool) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "feedID"))
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't parse feed id: %v", err))
+		return database.Feed{}, false
+	}
+
+	feed, err := apiCfg.DB.GetFeed(r.Context(), feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Feed not found")
+		return database.Feed{}, false
+	}
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get feed: %v", err))
+		return database.Feed{}, false
+	}
+	if feed.UserID != user.ID {
+		respondWithError(w, 403, "Only the user who created a feed can change it")
+		return database.Feed{}, false
+	}
+	return feed, true
+}
+
+// Absolute http or https URL, what the scraper can fetch
+func isHTTPURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
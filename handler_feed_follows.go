@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -12,6 +15,7 @@ import (
 )
 
 // Authenticated endpoint, need a User
+// Following a feed already followed isn't an error, responds with the existing follow and 200 instead of 201
 func (apiCfg *apiConfig) handlerCreateFeedFollow(w http.ResponseWriter, r *http.Request, user database.User) {
 	// Give as input is FeedID, tell us which feed they want
 	type parameters struct {
@@ -23,21 +27,51 @@ func (apiCfg *apiConfig) handlerCreateFeedFollow(w http.ResponseWriter, r *http.
 	err := decoder.Decode(&params)
 	if err != nil {
 		respondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
 	}
 
-	feedFollow, err := apiCfg.DB.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		UserID:    user.ID,
-		FeedID:    params.FeedID,
-	})
+	_, err = apiCfg.DB.GetFeed(r.Context(), params.FeedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Feed not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get feed: %v", err))
+		return
+	}
+
+	feedFollow, created, err := followFeed(r.Context(), apiCfg.DB, user.ID, params.FeedID)
 	if err != nil {
 		respondWithError(w, 400, fmt.Sprintf("Couldn't create feed follow: %v", err))
 		return
 	}
 
-	respondWithJSON(w, 201, databaseFeedFollowToFeedFollow(feedFollow))
+	code := 201
+	if !created {
+		code = 200
+	}
+	respondWithJSON(w, code, databaseFeedFollowToFeedFollow(feedFollow))
+}
+
+// Follow a feed, or get the existing follow if user already follows it
+// created is false when it already existed
+// Takes the queries to use so it can run inside a transaction
+func followFeed(ctx context.Context, db *database.Queries, userID, feedID uuid.UUID) (database.FeedFollow, bool, error) {
+	feedFollow, err := db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    userID,
+		FeedID:    feedID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		feedFollow, err = db.GetFeedFollowForFeed(ctx, database.GetFeedFollowForFeedParams{
+			UserID: userID,
+			FeedID: feedID,
+		})
+		return feedFollow, false, err
+	}
+	return feedFollow, err == nil, err
 }
 
 func (apiCfg *apiConfig) handlerGetFeedFollows(w http.ResponseWriter, r *http.Request, user database.User) {
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+	"github.com/lib/pq"
+)
+
+const maxFolderNameLength = 100
+
+// Body is {"name": "..."}, new folder goes after the existing ones
+func (apiCfg *apiConfig) handlerCreateFolder(w http.ResponseWriter, r *http.Request, user database.User) {
+	name, err := folderNameParam(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	folder, err := apiCfg.DB.CreateFolder(r.Context(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		Name:      name,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, fmt.Sprintf("Folder %q already exists", name))
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't create folder: %v", err))
+		return
+	}
+	respondWithJSON(w, 201, databaseFolderToFolder(folder))
+}
+
+// User's folders in order, each with its unread count
+func (apiCfg *apiConfig) handlerGetFolders(w http.ResponseWriter, r *http.Request, user database.User) {
+	folders, err := apiCfg.DB.GetFoldersForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get folders: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, databaseFoldersToFolders(folders))
+}
+
+// Body is {"name": "..."}
+func (apiCfg *apiConfig) handlerRenameFolder(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "folderID"))
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't parse folder id: %v", err))
+		return
+	}
+	name, err := folderNameParam(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	folder, err := apiCfg.DB.RenameFolder(r.Context(), database.RenameFolderParams{
+		ID:        folderID,
+		UserID:    user.ID,
+		Name:      name,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Folder not found")
+		return
+	}
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, fmt.Sprintf("Folder %q already exists", name))
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't rename folder: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, databaseFolderToFolder(folder))
+}
+
+// Body is {"folder_ids": [...]}, every folder the user has in the order they should be shown
+func (apiCfg *apiConfig) handlerReorderFolders(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		FolderIDs []uuid.UUID `json:"folder_ids"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	// Partial list would leave the rest with clashing positions
+	folders, err := apiCfg.DB.GetFoldersForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get folders: %v", err))
+		return
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, id := range params.FolderIDs {
+		seen[id] = true
+	}
+	if len(seen) != len(params.FolderIDs) || len(seen) != len(folders) {
+		respondWithError(w, 400, "folder_ids must list each of your folders exactly once")
+		return
+	}
+	for _, folder := range folders {
+		if !seen[folder.Folder.ID] {
+			respondWithError(w, 400, "folder_ids must list each of your folders exactly once")
+			return
+		}
+	}
+
+	_, err = apiCfg.DB.ReorderFolders(r.Context(), database.ReorderFoldersParams{
+		FolderIds: params.FolderIDs,
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't reorder folders: %v", err))
+		return
+	}
+
+	folders, err = apiCfg.DB.GetFoldersForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get folders: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, databaseFoldersToFolders(folders))
+}
+
+// Feeds in the folder stay followed, they're just not in it anymore
+func (apiCfg *apiConfig) handlerDeleteFolder(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "folderID"))
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't parse folder id: %v", err))
+		return
+	}
+
+	deleted, err := apiCfg.DB.DeleteFolder(r.Context(), database.DeleteFolderParams{
+		ID:     folderID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't delete folder: %v", err))
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Folder not found")
+		return
+	}
+	respondWithJSON(w, 200, struct{}{})
+}
+
+// File a follow in a folder, doing it again is fine
+func (apiCfg *apiConfig) handlerAddFeedFollowToFolder(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, feedFollowID, err := folderFeedFollowParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	added, err := apiCfg.DB.AddFeedFollowToFolder(r.Context(), database.AddFeedFollowToFolderParams{
+		FeedFollowID: feedFollowID,
+		UserID:       user.ID,
+		FolderID:     folderID,
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't add feed follow to folder: %v", err))
+		return
+	}
+	// Nothing inserted is either already in it, or folder or follow isn't the user's
+	if added == 0 {
+		follows, err := apiCfg.DB.GetFeedFollows(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, 400, fmt.Sprintf("Couldn't get feed follows: %v", err))
+			return
+		}
+		for _, follow := range follows {
+			if follow.FeedFollow.ID == feedFollowID && slices.Contains(follow.FolderIds, folderID) {
+				respondWithJSON(w, 200, struct{}{})
+				return
+			}
+		}
+		respondWithError(w, 404, "Folder or feed follow not found")
+		return
+	}
+	respondWithJSON(w, 200, struct{}{})
+}
+
+// Take a follow out of a folder, still followed afterwards
+func (apiCfg *apiConfig) handlerRemoveFeedFollowFromFolder(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, feedFollowID, err := folderFeedFollowParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	_, err = apiCfg.DB.RemoveFeedFollowFromFolder(r.Context(), database.RemoveFeedFollowFromFolderParams{
+		FeedFollowID: feedFollowID,
+		FolderID:     folderID,
+		UserID:       user.ID,
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't remove feed follow from folder: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, struct{}{})
+}
+
+// Name from a {"name": "..."} body, trimmed
+func folderNameParam(r *http.Request) (string, error) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing JSON: %v", err)
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return "", errors.New("Folder name is required")
+	}
+	if len(name) > maxFolderNameLength {
+		return "", fmt.Errorf("Folder name can be at most %d characters", maxFolderNameLength)
+	}
+	return name, nil
+}
+
+// IDs from /folders/{folderID}/feed_follows/{feedFollowID}
+func folderFeedFollowParams(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "folderID"))
+	if err != nil {
+		return uuid.UUID{}, uuid.UUID{}, fmt.Errorf("Couldn't parse folder id: %v", err)
+	}
+	feedFollowID, err := uuid.Parse(chi.URLParam(r, "feedFollowID"))
+	if err != nil {
+		return uuid.UUID{}, uuid.UUID{}, fmt.Errorf("Couldn't parse feed follow id: %v", err)
+	}
+	return folderID, feedFollowID, nil
+}
+
+// Postgres unique_violation, e.g. a name that's already taken
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+)
+
+// Biggest OPML file we accept, thousands of subscriptions fit easily
+const maxOPMLSize = 5 << 20
+
+// What happened to one entry of an imported OPML file
+type opmlImportResult struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	// Folder it was filed in, from the outline it was nested in
+	Folder *string `json:"folder"`
+	// followed, already_following or failed
+	Status      string     `json:"status"`
+	FeedID      *uuid.UUID `json:"feed_id,omitempty"`
+	FeedCreated bool       `json:"feed_created"`
+	Error       string     `json:"error,omitempty"`
+}
+
+const (
+	opmlStatusFollowed         = "followed"
+	opmlStatusAlreadyFollowing = "already_following"
+	opmlStatusFailed           = "failed"
+)
+
+// Import subscriptions from another reader
+// Body is the OPML file itself, or a multipart form with the file in "file"
+// Feeds that already exist are reused by URL, the rest are created, then all of them are followed
+// One bad entry doesn't stop the rest, every entry gets its own result
+func (apiCfg *apiConfig) handlerImportOPML(w http.ResponseWriter, r *http.Request, user database.User) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxOPMLSize)
+
+	var file io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		formFile, _, err := r.FormFile("file")
+		if err != nil {
+			respondWithError(w, 400, fmt.Sprintf("Couldn't read file from form: %v", err))
+			return
+		}
+		defer formFile.Close()
+		file = formFile
+	}
+
+	opml := OPML{}
+	err := xml.NewDecoder(file).Decode(&opml)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Error parsing OPML: %v", err))
+		return
+	}
+
+	results := []opmlImportResult{}
+	for _, entry := range opml.entries() {
+		results = append(results, apiCfg.importOPMLEntry(r, user, entry))
+	}
+
+	type response struct {
+		Followed         int                `json:"followed"`
+		AlreadyFollowing int                `json:"already_following"`
+		Failed           int                `json:"failed"`
+		Results          []opmlImportResult `json:"results"`
+	}
+	resp := response{Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case opmlStatusFollowed:
+			resp.Followed++
+		case opmlStatusAlreadyFollowing:
+			resp.AlreadyFollowing++
+		case opmlStatusFailed:
+			resp.Failed++
+		}
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// Find or create the entry's feed and follow it
+func (apiCfg *apiConfig) importOPMLEntry(r *http.Request, user database.User, entry opmlEntry) opmlImportResult {
+	result := opmlImportResult{
+		URL:    entry.URL,
+		Title:  entry.Title,
+		Folder: nullStringToStringPtr(nullString(entry.Category)),
+		Status: opmlStatusFailed,
+	}
+
+	if !isHTTPURL(entry.URL) {
+		result.Error = "not an http or https URL"
+		return result
+	}
+
+	feed, err := apiCfg.DB.GetFeedByURL(r.Context(), entry.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		feed, err = apiCfg.DB.CreateFeed(r.Context(), database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			// Feeds need a name, URL will do if the file didn't give one
+			Name:   firstNonEmpty(entry.Title, entry.URL),
+			Url:    entry.URL,
+			UserID: user.ID,
+		})
+		result.FeedCreated = err == nil
+	}
+	if err != nil {
+		result.Error = fmt.Sprintf("Couldn't create feed: %v", err)
+		return result
+	}
+	result.FeedID = &feed.ID
+
+	_, followed, err := followFeed(r.Context(), apiCfg.DB, user.ID, feed.ID)
+	if err != nil {
+		result.Error = fmt.Sprintf("Couldn't follow feed: %v", err)
+		return result
+	}
+
+	// Also files feeds that were already followed, so importing again picks up new folders
+	if entry.Category != "" {
+		folder, err := apiCfg.DB.UpsertFolder(r.Context(), database.UpsertFolderParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			UserID:    user.ID,
+			Name:      entry.Category,
+		})
+		if err == nil {
+			err = apiCfg.DB.AddFollowedFeedToFolder(r.Context(), database.AddFollowedFeedToFolderParams{
+				FeedID:   feed.ID,
+				UserID:   user.ID,
+				FolderID: folder.ID,
+			})
+		}
+		if err != nil {
+			result.Error = fmt.Sprintf("Couldn't add feed to folder: %v", err)
+			return result
+		}
+	}
+
+	result.Status = opmlStatusFollowed
+	if !followed {
+		result.Status = opmlStatusAlreadyFollowing
+	}
+	return result
+}
+
+// Export the feeds the user follows as OPML, so they can be imported into another reader
+// Feeds in folders are nested under an outline for each folder, a feed in several folders shows up in each
+func (apiCfg *apiConfig) handlerExportOPML(w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := apiCfg.DB.GetFollowedFeedsForExport(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get feed follows: %v", err))
+		return
+	}
+
+	opml := OPML{
+		Version: "2.0",
+		Head: OPMLHead{
+			Title:       fmt.Sprintf("%s subscriptions", user.Name),
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+	// Rows come ordered by folder, so each folder's feeds are together
+	folders := map[string]int{}
+	for _, feed := range feeds {
+		outline := OPMLOutline{
+			Text:   feed.Name,
+			Title:  feed.Name,
+			Type:   "rss",
+			XMLURL: feed.Url,
+		}
+		if !feed.FolderName.Valid {
+			opml.Body.Outlines = append(opml.Body.Outlines, outline)
+			continue
+		}
+		i, ok := folders[feed.FolderName.String]
+		if !ok {
+			opml.Body.Outlines = append(opml.Body.Outlines, OPMLOutline{
+				Text:  feed.FolderName.String,
+				Title: feed.FolderName.String,
+			})
+			i = len(opml.Body.Outlines) - 1
+			folders[feed.FolderName.String] = i
+		}
+		opml.Body.Outlines[i].Outlines = append(opml.Body.Outlines[i].Outlines, outline)
+	}
+
+	dat, err := xml.MarshalIndent(opml, "", "  ")
+	if err != nil {
+		respondWithError(w, 500, fmt.Sprintf("Couldn't build OPML: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.opml"`)
+	w.WriteHeader(200)
+	w.Write([]byte(xml.Header))
+	w.Write(dat)
+}
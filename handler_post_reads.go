@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+)
+
+// Most posts that can be marked in one request
+const maxBulkPostIDs = 1000
+
+// Mark one post read
+// Posts the user doesn't follow the feed of are ignored
+func (apiCfg *apiConfig) handlerMarkPostRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(chi.URLParam(r, "postID"))
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't parse post id: %v", err))
+		return
+	}
+
+	_, err = apiCfg.DB.MarkPostsRead(r.Context(), database.MarkPostsReadParams{
+		ReadAt:  time.Now().UTC(),
+		UserID:  user.ID,
+		PostIds: []uuid.UUID{postID},
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't mark post read: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, struct{}{})
+}
+
+// Mark one post unread again
+func (apiCfg *apiConfig) handlerMarkPostUnread(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(chi.URLParam(r, "postID"))
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't parse post id: %v", err))
+		return
+	}
+
+	_, err = apiCfg.DB.MarkPostsUnread(r.Context(), database.MarkPostsUnreadParams{
+		UserID:  user.ID,
+		PostIds: []uuid.UUID{postID},
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't mark post unread: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, struct{}{})
+}
+
+// Body for marking several posts at once
+type bulkPostIDs struct {
+	PostIDs []uuid.UUID `json:"post_ids"`
+}
+
+func decodeBulkPostIDs(r *http.Request) ([]uuid.UUID, error) {
+	params := bulkPostIDs{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing JSON: %v", err)
+	}
+	if len(params.PostIDs) == 0 {
+		return nil, fmt.Errorf("post_ids is required")
+	}
+	if len(params.PostIDs) > maxBulkPostIDs {
+		return nil, fmt.Errorf("At most %d post_ids at a time", maxBulkPostIDs)
+	}
+	return params.PostIDs, nil
+}
+
+// Mark several posts read, body {"post_ids": [...]}
+// Responds with how many posts went from unread to read
+func (apiCfg *apiConfig) handlerMarkPostsRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	postIDs, err := decodeBulkPostIDs(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	marked, err := apiCfg.DB.MarkPostsRead(r.Context(), database.MarkPostsReadParams{
+		ReadAt:  time.Now().UTC(),
+		UserID:  user.ID,
+		PostIds: postIDs,
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't mark posts read: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, markedResponse{Marked: marked})
+}
+
+// Mark several posts unread, body {"post_ids": [...]}
+// Responds with how many posts went from read to unread
+func (apiCfg *apiConfig) handlerMarkPostsUnread(w http.ResponseWriter, r *http.Request, user database.User) {
+	postIDs, err := decodeBulkPostIDs(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	marked, err := apiCfg.DB.MarkPostsUnread(r.Context(), database.MarkPostsUnreadParams{
+		UserID:  user.ID,
+		PostIds: postIDs,
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't mark posts unread: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, markedResponse{Marked: marked})
+}
+
+// Mark everything published up to a time read, body {"up_to": "...", "feed_id": "..."}
+// up_to defaults to now, leaving out feed_id marks posts in every followed feed
+func (apiCfg *apiConfig) handlerMarkAllPostsRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		UpTo   *time.Time `json:"up_to"`
+		FeedID *uuid.UUID `json:"feed_id"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	// Empty body marks everything read
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	upTo := time.Now().UTC()
+	if params.UpTo != nil {
+		// Database stores UTC without a zone
+		upTo = params.UpTo.UTC()
+	}
+	feedID := uuid.NullUUID{}
+	if params.FeedID != nil {
+		feedID = uuid.NullUUID{UUID: *params.FeedID, Valid: true}
+	}
+
+	marked, err := apiCfg.DB.MarkAllPostsRead(r.Context(), database.MarkAllPostsReadParams{
+		ReadAt: time.Now().UTC(),
+		UserID: user.ID,
+		UpTo:   upTo,
+		FeedID: feedID,
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't mark posts read: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, markedResponse{Marked: marked})
+}
+
+type markedResponse struct {
+	Marked int64 `json:"marked"`
+}
+
+// Unread posts in each followed feed
+// ?folder_id= for only the feeds in one folder
+func (apiCfg *apiConfig) handlerGetUnreadCounts(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := uuidParam(r, "folder_id")
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	counts, err := apiCfg.DB.GetUnreadCountsForUser(r.Context(), database.GetUnreadCountsForUserParams{
+		UserID:   user.ID,
+		FolderID: folderID,
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get unread counts: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, databaseUnreadCountsToUnreadCounts(counts))
+}
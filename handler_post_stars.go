@@ -0,0 +1,155 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+)
+
+// Limits on what can be stored with a star
+const (
+	maxStarNoteLength  = 10000
+	maxStarLabels      = 20
+	maxStarLabelLength = 50
+)
+
+// Star a post, or update note and labels of a post already starred
+// Body is optional: {"note": "...", "labels": ["..."]}
+func (apiCfg *apiConfig) handlerStarPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(chi.URLParam(r, "postID"))
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't parse post id: %v", err))
+		return
+	}
+
+	type parameters struct {
+		Note   string   `json:"note"`
+		Labels []string `json:"labels"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	// Empty body just stars the post
+	if err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, 400, fmt.Sprintf("Error parsing JSON: %v", err))
+		return
+	}
+
+	note := strings.TrimSpace(params.Note)
+	if len(note) > maxStarNoteLength {
+		respondWithError(w, 400, fmt.Sprintf("Note can be at most %d characters", maxStarNoteLength))
+		return
+	}
+	labels, err := cleanStarLabels(params.Labels)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	star, err := apiCfg.DB.StarPost(r.Context(), database.StarPostParams{
+		UserID: user.ID,
+		Now:    time.Now().UTC(),
+		Note:   nullString(note),
+		Labels: labels,
+		PostID: postID,
+	})
+	// Post doesn't exist or is in a feed the user doesn't follow
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Post not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't star post: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, databasePostStarToPostStar(star))
+}
+
+// Trim labels, drop blank and duplicate ones
+func cleanStarLabels(labels []string) ([]string, error) {
+	cleaned := []string{}
+	seen := map[string]bool{}
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || seen[label] {
+			continue
+		}
+		if len(label) > maxStarLabelLength {
+			return nil, fmt.Errorf("Labels can be at most %d characters", maxStarLabelLength)
+		}
+		seen[label] = true
+		cleaned = append(cleaned, label)
+	}
+	if len(cleaned) > maxStarLabels {
+		return nil, fmt.Errorf("At most %d labels per post", maxStarLabels)
+	}
+	return cleaned, nil
+}
+
+func (apiCfg *apiConfig) handlerUnstarPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(chi.URLParam(r, "postID"))
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't parse post id: %v", err))
+		return
+	}
+
+	_, err = apiCfg.DB.UnstarPost(r.Context(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: postID,
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't unstar post: %v", err))
+		return
+	}
+	respondWithJSON(w, 200, struct{}{})
+}
+
+// Most recently starred first, paged like GET /v1/posts
+// Query parameters, all optional:
+// limit, cursor: same as GET /v1/posts
+// label: only posts with this label
+func (apiCfg *apiConfig) handlerGetStarredPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	params := database.GetStarredPostsForUserParams{
+		UserID: user.ID,
+		Label:  nullString(r.URL.Query().Get("label")),
+	}
+
+	pageSize, err := pageSizeParam(r, apiCfg.PostsLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	// Ask for one extra to know if there's another page
+	params.PageSize = pageSize + 1
+
+	cursor, err := cursorParam(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if cursor != nil {
+		params.CursorStarredAt = sql.NullTime{Time: cursor.At, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	posts, err := apiCfg.DB.GetStarredPostsForUser(r.Context(), params)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get starred posts: %v", err))
+		return
+	}
+
+	if len(posts) > int(pageSize) {
+		posts = posts[:pageSize]
+		last := posts[len(posts)-1]
+		setNextPageLink(w, r, pageCursor{At: last.PostStar.CreatedAt, ID: last.PostStar.PostID})
+	}
+	respondWithJSON(w, 200, databaseStarredPostsToPosts(posts))
+}
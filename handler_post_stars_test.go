@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+)
+
+func TestStarsSurviveFeedDeletion(t *testing.T) {
+	apiCfg := testAPIConfig(t)
+	ctx := context.Background()
+	user := testUser(t, apiCfg)
+
+	feed, err := apiCfg.DB.CreateFeed(ctx, database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		Name:      "Deleted soon",
+		Url:       "https://example.com/" + uuid.NewString() + ".xml",
+		UserID:    user.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = followFeed(ctx, apiCfg.DB, user.ID, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	postID := uuid.New()
+	postURL := "https://example.com/worth-keeping"
+	_, err = apiCfg.DB.CreatePost(ctx, database.CreatePostParams{
+		ID:                postID,
+		CreatedAt:         time.Now().UTC(),
+		UpdatedAt:         time.Now().UTC(),
+		Title:             "Worth keeping",
+		Description:       sql.NullString{String: "Still here", Valid: true},
+		PublishedAt:       publishedAt,
+		Url:               postURL,
+		FeedID:            feed.ID,
+		PublishedAtSource: publishedAtSourcePublished,
+		Guid:              "worth-keeping",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = apiCfg.DB.StarPost(ctx, database.StarPostParams{
+		UserID: user.ID,
+		Now:    time.Now().UTC(),
+		Note:   sql.NullString{String: "read later", Valid: true},
+		Labels: []string{"go"},
+		PostID: postID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r := withURLParams(httptest.NewRequest("DELETE", "/v1/feeds/"+feed.ID.String(), nil), map[string]string{"feedID": feed.ID.String()})
+	apiCfg.handlerDeleteFeed(w, r, user)
+	if w.Code != 200 {
+		t.Fatalf("deleting feed: status %d, body %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	apiCfg.handlerGetStarredPosts(w, httptest.NewRequest("GET", "/v1/posts/starred", nil), user)
+	if w.Code != 200 {
+		t.Fatalf("getting starred posts: status %d, body %s", w.Code, w.Body)
+	}
+	posts := []Post{}
+	err = json.NewDecoder(w.Body).Decode(&posts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 {
+		t.Fatalf("got %d starred posts after deleting feed, want 1", len(posts))
+	}
+	got := posts[0]
+	if got.ID != postID || got.Title != "Worth keeping" || got.Url != postURL || !got.PublishedAt.Equal(publishedAt) {
+		t.Errorf("starred post = %+v, want copy of the deleted post", got)
+	}
+	if got.Description == nil || *got.Description != "Still here" {
+		t.Errorf("description = %v, want %q", got.Description, "Still here")
+	}
+	if got.Star == nil || !got.Star.PostDeleted || got.Star.Note == nil || *got.Star.Note != "read later" {
+		t.Errorf("star = %+v, want post_deleted with note kept", got.Star)
+	}
+
+	// Can still be unstarred by the post's ID
+	_, err = apiCfg.DB.UnstarPost(ctx, database.UnstarPostParams{UserID: user.ID, PostID: postID})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
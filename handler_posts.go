@@ -0,0 +1,96 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+)
+
+// Previous versions of a post, saved whenever the publisher changed it
+func (apiCfg *apiConfig) handlerGetPostRevisions(w http.ResponseWriter, r *http.Request, user database.User) {
+	postIDStr := chi.URLParam(r, "postID")
+	postID, err := uuid.Parse(postIDStr)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't parse post id: %v", err))
+		return
+	}
+
+	// Only returns revisions for posts in feeds the user follows
+	revisions, err := apiCfg.DB.GetPostRevisionsForUser(r.Context(), database.GetPostRevisionsForUserParams{
+		PostID: postID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't get post revisions: %v", err))
+		return
+	}
+
+	respondWithJSON(w, 200, databasePostRevisionsToPostRevisions(revisions))
+}
+
+// Longest search query we accept
+const maxSearchQueryLength = 256
+
+// Full-text search over posts in feeds the user follows, best matches first
+// Query parameters:
+// q: what to search for, required. Supports "quoted phrases", or and -excluded words
+// limit, cursor: same as GET /v1/posts
+// feed_id: only posts from this feed
+func (apiCfg *apiConfig) handlerSearchPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, 400, "q is required")
+		return
+	}
+	if len(query) > maxSearchQueryLength {
+		respondWithError(w, 400, fmt.Sprintf("q can be at most %d characters", maxSearchQueryLength))
+		return
+	}
+
+	params := database.SearchPostsForUserParams{
+		Query:  query,
+		UserID: user.ID,
+	}
+
+	pageSize, err := pageSizeParam(r, apiCfg.PostsLimit)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	// Ask for one extra to know if there's another page
+	params.PageSize = pageSize + 1
+
+	cursor, err := cursorParam(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if cursor != nil {
+		params.CursorRank = sql.NullFloat64{Float64: float64(cursor.Rank), Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	params.FeedID, err = uuidParam(r, "feed_id")
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	results, err := apiCfg.DB.SearchPostsForUser(r.Context(), params)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Couldn't search posts: %v", err))
+		return
+	}
+
+	if len(results) > int(pageSize) {
+		results = results[:pageSize]
+		last := results[len(results)-1]
+		setNextPageLink(w, r, pageCursor{Rank: last.Rank, ID: last.Post.ID})
+	}
+	respondWithJSON(w, 200, databaseSearchResultsToSearchResults(results))
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -62,15 +64,89 @@ func (apiCfg *apiConfig) handleGetUser(w http.ResponseWriter, r *http.Request, u
 	respondWithJSON(w, 200, databaseUserToUser(user))
 }
 
+// Newest posts first, a page at a time
+// Query parameters, all optional:
+// limit: posts per page, capped at maxPageSize
+// cursor: from Link header of previous page
+// feed_id: only posts from this feed
+// folder_id: only posts from feeds in this folder
+// since, until: only posts published in [since, until)
+// unread: true for only posts the user hasn't read
 func (apiCfg *apiConfig) handlerGetPostsForUser(w http.ResponseWriter, r *http.Request, user database.User) {
-	posts, err := apiCfg.DB.GetPostsForUser(r.Context(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  10,
-	})
+	params, err := apiCfg.postsPageParams(r, user)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	pageSize := params.PageSize
+	// Ask for one extra to know if there's another page
+	params.PageSize++
+
+	posts, err := apiCfg.DB.GetPostsForUser(r.Context(), params)
 	if err != nil {
 		respondWithError(w, 400, fmt.Sprintf("Couldn't get posts: %v", err))
 		return
 	}
 
+	if len(posts) > int(pageSize) {
+		posts = posts[:pageSize]
+		last := posts[len(posts)-1]
+		setNextPageLink(w, r, pageCursor{At: last.Post.PublishedAt, ID: last.Post.ID})
+	}
 	respondWithJSON(w, 200, databasePostsToPosts(posts))
 }
+
+// Read pagination and filter query parameters
+func (apiCfg *apiConfig) postsPageParams(r *http.Request, user database.User) (database.GetPostsForUserParams, error) {
+	params := database.GetPostsForUserParams{
+		UserID: user.ID,
+	}
+
+	pageSize, err := pageSizeParam(r, apiCfg.PostsLimit)
+	if err != nil {
+		return params, err
+	}
+	params.PageSize = pageSize
+
+	cursor, err := cursorParam(r)
+	if err != nil {
+		return params, err
+	}
+	if cursor != nil {
+		params.CursorPublishedAt = sql.NullTime{Time: cursor.At, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+	}
+
+	params.FeedID, err = uuidParam(r, "feed_id")
+	if err != nil {
+		return params, err
+	}
+	params.FolderID, err = uuidParam(r, "folder_id")
+	if err != nil {
+		return params, err
+	}
+
+	if unreadStr := r.URL.Query().Get("unread"); unreadStr != "" {
+		unread, err := strconv.ParseBool(unreadStr)
+		if err != nil {
+			return params, fmt.Errorf("unread must be true or false")
+		}
+		params.UnreadOnly = unread
+	}
+
+	since, err := timeParam(r, "since")
+	if err != nil {
+		return params, err
+	}
+	if since != nil {
+		params.Since = sql.NullTime{Time: *since, Valid: true}
+	}
+	until, err := timeParam(r, "until")
+	if err != nil {
+		return params, err
+	}
+	if until != nil {
+		params.Until = sql.NullTime{Time: *until, Valid: true}
+	}
+	return params, nil
+}
@@ -7,14 +7,17 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
 VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, feed_id) DO NOTHING
 RETURNING id, created_at, updated_at, user_id, feed_id
 `
 
@@ -26,6 +29,7 @@ type CreateFeedFollowParams struct {
 	FeedID    uuid.UUID
 }
 
+// Already following returns no rows, use GetFeedFollowForFeed to get the existing follow
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (FeedFollow, error) {
 	row := q.db.QueryRowContext(ctx, createFeedFollow,
 		arg.ID,
@@ -63,25 +67,70 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 	return err
 }
 
+const getFeedFollowForFeed = `-- name: GetFeedFollowForFeed :one
+SELECT id, created_at, updated_at, user_id, feed_id FROM feed_follows WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowForFeedParams struct {
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollowForFeed(ctx context.Context, arg GetFeedFollowForFeedParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollowForFeed, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+	)
+	return i, err
+}
+
 const getFeedFollows = `-- name: GetFeedFollows :many
-SELECT id, created_at, updated_at, user_id, feed_id FROM feed_follows WHERE user_id = $1
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
+ARRAY(
+    SELECT feed_follow_folders.folder_id FROM feed_follow_folders
+    WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+)::uuid[] AS folder_ids,
+(
+    SELECT COUNT(*) FROM posts
+    WHERE posts.feed_id = feed_follows.feed_id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+    )
+) AS unread_count
+FROM feed_follows WHERE user_id = $1
 `
 
-func (q *Queries) GetFeedFollows(ctx context.Context, userID uuid.UUID) ([]FeedFollow, error) {
+type GetFeedFollowsRow struct {
+	FeedFollow  FeedFollow
+	FolderIds   []uuid.UUID
+	UnreadCount int64
+}
+
+// Folders the follow is filed in
+// Posts in the feed this user hasn't read yet
+func (q *Queries) GetFeedFollows(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsRow, error) {
 	rows, err := q.db.QueryContext(ctx, getFeedFollows, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []FeedFollow
+	var items []GetFeedFollowsRow
 	for rows.Next() {
-		var i FeedFollow
+		var i GetFeedFollowsRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.UserID,
-			&i.FeedID,
+			&i.FeedFollow.ID,
+			&i.FeedFollow.CreatedAt,
+			&i.FeedFollow.UpdatedAt,
+			&i.FeedFollow.UserID,
+			&i.FeedFollow.FeedID,
+			pq.Array(&i.FolderIds),
+			&i.UnreadCount,
 		); err != nil {
 			return nil, err
 		}
@@ -95,3 +144,66 @@ func (q *Queries) GetFeedFollows(ctx context.Context, userID uuid.UUID) ([]FeedF
 	}
 	return items, nil
 }
+
+const getFollowedFeedsForExport = `-- name: GetFollowedFeedsForExport :many
+SELECT feeds.name, feeds.url, folders.name AS folder_name
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+LEFT JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+LEFT JOIN folders ON folders.id = feed_follow_folders.folder_id
+WHERE feed_follows.user_id = $1
+ORDER BY folders.position NULLS FIRST, folders.name, lower(feeds.name)
+`
+
+type GetFollowedFeedsForExportRow struct {
+	Name       string
+	Url        string
+	FolderName sql.NullString
+}
+
+// Everything an OPML export needs, grouped by folder
+// A follow in several folders comes back once for each, unfiled ones have no folder name
+func (q *Queries) GetFollowedFeedsForExport(ctx context.Context, userID uuid.UUID) ([]GetFollowedFeedsForExportRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedFeedsForExport, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowedFeedsForExportRow
+	for rows.Next() {
+		var i GetFollowedFeedsForExportRow
+		if err := rows.Scan(&i.Name, &i.Url, &i.FolderName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = $1, updated_at = $2
+WHERE feed_id = $3
+AND user_id NOT IN (
+    SELECT user_id FROM feed_follows WHERE feed_id = $1
+)
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	UpdatedAt  time.Time
+	FromFeedID uuid.UUID
+}
+
+// Merging feeds, move follows from one feed to another
+// Users already following the other feed keep their existing follow
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.UpdatedAt, arg.FromFeedID)
+	return err
+}
@@ -7,35 +7,31 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+const claimFeed = `-- name: ClaimFeed :one
+UPDATE feeds
+SET last_fetched_at = $1::timestamp, updated_at = $1::timestamp, lease_expires_at = $2
+WHERE id = $3 AND (lease_expires_at IS NULL OR lease_expires_at <= $1::timestamp)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, last_error, consecutive_failures, last_success_at, disabled_at, lease_expires_at, description, site_url, language, image_url, generator, ttl_minutes, skip_hours, skip_days
 `
 
-type CreateFeedParams struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserID    uuid.UUID
+type ClaimFeedParams struct {
+	Now            time.Time
+	LeaseExpiresAt sql.NullTime
+	ID             uuid.UUID
 }
 
-func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, createFeed,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Name,
-		arg.Url,
-		arg.UserID,
-	)
+// Claim one specific feed, whether it's due or not
+// Used when fetching a feed on demand, still respects other instances' leases
+// now comes from Go, same as in ClaimFeedsToFetch
+func (q *Queries) ClaimFeed(ctx context.Context, arg ClaimFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimFeed, arg.Now, arg.LeaseExpiresAt, arg.ID)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -45,18 +41,61 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.LeaseExpiresAt,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
 	)
 	return i, err
 }
 
-const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at = $1::timestamp, updated_at = $1::timestamp, lease_expires_at = $2
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE (next_fetch_at IS NULL OR next_fetch_at <= $1::timestamp) AND disabled_at IS NULL
+    AND (lease_expires_at IS NULL OR lease_expires_at <= $1::timestamp)
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, last_error, consecutive_failures, last_success_at, disabled_at, lease_expires_at, description, site_url, language, image_url, generator, ttl_minutes, skip_hours, skip_days
 `
 
-// User to get all of the feeds
-// Not an authenticated endpoint
-func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
-	rows, err := q.db.QueryContext(ctx, getFeeds)
+type ClaimFeedsToFetchParams struct {
+	Now            time.Time
+	LeaseExpiresAt sql.NullTime
+	Limit          int32
+}
+
+// Purpose is to get feeds that next need to be fetched and claim them in one statement
+// Find any feeds that have never been fetched before, priority
+// If every feed fetched, find fetched longest ago
+// Many goroutines, and many server instances, fetch different feeds
+// For auditing purposes
+// Lease stops other instances claiming feed while we fetch it
+// now comes from Go, next_fetch_at and lease_expires_at are UTC from Go too
+// NOW() would be in the session's time zone and not line up with them
+// Only feeds that are due, never scheduled means due now
+// Disabled feeds failed too many times, stop fetching them
+// Nobody else is fetching it, or whoever was crashed and lease ran out
+// Pass in how many feeds we want
+// Rows another instance is claiming right now are skipped instead of waited on
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.Now, arg.LeaseExpiresAt, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -72,6 +111,22 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.LeaseExpiresAt,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.TtlMinutes,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
 		); err != nil {
 			return nil, err
 		}
@@ -86,19 +141,174 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 	return items, nil
 }
 
-const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT $1
+const createFeed = `-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, description, site_url, language, image_url, generator)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, last_error, consecutive_failures, last_success_at, disabled_at, lease_expires_at, description, site_url, language, image_url, generator, ttl_minutes, skip_hours, skip_days
 `
 
-// Purpose is to get feed that next needs to be fetched
-// Find any feeds that have never been fetched before, priority
-// If every feed fetched, find fetched longest ago
-// Many goroutines to fetch different feeds
-// Pass in how many feeds we want
-func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed, error) {
-	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, limit)
+type CreateFeedParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Name        string
+	Url         string
+	UserID      uuid.UUID
+	Description sql.NullString
+	SiteUrl     sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	Generator   sql.NullString
+}
+
+func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, createFeed,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+		arg.Description,
+		arg.SiteUrl,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.LeaseExpiresAt,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+	)
+	return i, err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE id = $1
+`
+
+// Follows and posts are deleted with it, ON DELETE CASCADE
+// Stars aren't, they keep a copy of their post
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const disableFeed = `-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = $1::timestamp, updated_at = $1::timestamp
+WHERE id = $2
+`
+
+type DisableFeedParams struct {
+	Now time.Time
+	ID  uuid.UUID
+}
+
+func (q *Queries) DisableFeed(ctx context.Context, arg DisableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, disableFeed, arg.Now, arg.ID)
+	return err
+}
+
+const getFeed = `-- name: GetFeed :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, last_error, consecutive_failures, last_success_at, disabled_at, lease_expires_at, description, site_url, language, image_url, generator, ttl_minutes, skip_hours, skip_days FROM feeds WHERE id = $1
+`
+
+func (q *Queries) GetFeed(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeed, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.LeaseExpiresAt,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+	)
+	return i, err
+}
+
+const getFeedByURL = `-- name: GetFeedByURL :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, last_error, consecutive_failures, last_success_at, disabled_at, lease_expires_at, description, site_url, language, image_url, generator, ttl_minutes, skip_hours, skip_days FROM feeds WHERE url = $1
+`
+
+func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByURL, url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.LeaseExpiresAt,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+	)
+	return i, err
+}
+
+const getFeeds = `-- name: GetFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, last_error, consecutive_failures, last_success_at, disabled_at, lease_expires_at, description, site_url, language, image_url, generator, ttl_minutes, skip_hours, skip_days FROM feeds
+`
+
+// User to get all of the feeds
+// Not an authenticated endpoint
+func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeeds)
 	if err != nil {
 		return nil, err
 	}
@@ -114,6 +324,22 @@ func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed,
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.NextFetchAt,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.LeaseExpiresAt,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.TtlMinutes,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
 		); err != nil {
 			return nil, err
 		}
@@ -128,16 +354,249 @@ func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed,
 	return items, nil
 }
 
-const markFeedAsFetched = `-- name: MarkFeedAsFetched :one
+const recordFeedFetchFailure = `-- name: RecordFeedFetchFailure :one
 UPDATE feeds
-SET last_fetched_at = NOW(), updated_at = NOW()
+SET consecutive_failures = consecutive_failures + 1, last_error = $2
 WHERE id = $1
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, last_error, consecutive_failures, last_success_at, disabled_at, lease_expires_at, description, site_url, language, image_url, generator, ttl_minutes, skip_hours, skip_days
 `
 
-// For auditing purposes
-func (q *Queries) MarkFeedAsFetched(ctx context.Context, id uuid.UUID) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, markFeedAsFetched, id)
+type RecordFeedFetchFailureParams struct {
+	ID        uuid.UUID
+	LastError sql.NullString
+}
+
+// Returns updated feed so caller knows how many times in a row it's failed
+func (q *Queries) RecordFeedFetchFailure(ctx context.Context, arg RecordFeedFetchFailureParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFetchFailure, arg.ID, arg.LastError)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.LeaseExpiresAt,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+	)
+	return i, err
+}
+
+const recordFeedFetchSuccess = `-- name: RecordFeedFetchSuccess :exec
+UPDATE feeds
+SET last_success_at = $1::timestamp, consecutive_failures = 0, last_error = NULL, disabled_at = NULL
+WHERE id = $2
+`
+
+type RecordFeedFetchSuccessParams struct {
+	Now time.Time
+	ID  uuid.UUID
+}
+
+// Feed is healthy again, reset failure tracking
+// Re-enables a disabled feed that was fetched on demand and works again
+// now comes from Go like every other timestamp we show
+func (q *Queries) RecordFeedFetchSuccess(ctx context.Context, arg RecordFeedFetchSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFetchSuccess, arg.Now, arg.ID)
+	return err
+}
+
+const releaseFeedLease = `-- name: ReleaseFeedLease :exec
+UPDATE feeds
+SET lease_expires_at = NULL
+WHERE id = $1
+`
+
+// Done fetching, feed can be claimed again once it's due
+func (q *Queries) ReleaseFeedLease(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedLease, id)
+	return err
+}
+
+const setFeedNextFetchAt = `-- name: SetFeedNextFetchAt :exec
+UPDATE feeds
+SET next_fetch_at = $2
+WHERE id = $1
+`
+
+type SetFeedNextFetchAtParams struct {
+	ID          uuid.UUID
+	NextFetchAt sql.NullTime
+}
+
+// Schedule next fetch once we know how often feed changes
+func (q *Queries) SetFeedNextFetchAt(ctx context.Context, arg SetFeedNextFetchAtParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetchAt, arg.ID, arg.NextFetchAt)
+	return err
+}
+
+const updateFeed = `-- name: UpdateFeed :one
+UPDATE feeds
+SET name = $1,
+    etag = CASE WHEN url = $2 THEN etag END,
+    last_modified = CASE WHEN url = $2 THEN last_modified END,
+    next_fetch_at = CASE WHEN url = $2 THEN next_fetch_at END,
+    last_error = CASE WHEN url = $2 THEN last_error END,
+    consecutive_failures = CASE WHEN url = $2 THEN consecutive_failures ELSE 0 END,
+    disabled_at = CASE WHEN url = $2 THEN disabled_at END,
+    url = $2,
+    updated_at = $3
+WHERE id = $4
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, last_error, consecutive_failures, last_success_at, disabled_at, lease_expires_at, description, site_url, language, image_url, generator, ttl_minutes, skip_hours, skip_days
+`
+
+type UpdateFeedParams struct {
+	Name      string
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Changing URL starts fetch state over, it's fetched again on next round
+func (q *Queries) UpdateFeed(ctx context.Context, arg UpdateFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeed,
+		arg.Name,
+		arg.Url,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.LeaseExpiresAt,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+	)
+	return i, err
+}
+
+const updateFeedCacheHeaders = `-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3, updated_at = $4
+WHERE id = $1
+`
+
+type UpdateFeedCacheHeadersParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+	UpdatedAt    time.Time
+}
+
+// Store ETag and Last-Modified so next fetch can be conditional
+func (q *Queries) UpdateFeedCacheHeaders(ctx context.Context, arg UpdateFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheHeaders,
+		arg.ID,
+		arg.Etag,
+		arg.LastModified,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
+const updateFeedFetchHints = `-- name: UpdateFeedFetchHints :exec
+UPDATE feeds
+SET ttl_minutes = $2, skip_hours = $3, skip_days = $4
+WHERE id = $1
+`
+
+type UpdateFeedFetchHintsParams struct {
+	ID         uuid.UUID
+	TtlMinutes int32
+	SkipHours  []int32
+	SkipDays   []int32
+}
+
+// ttl, skipHours and skipDays from the last full fetch, a 304 has no body to read them from
+func (q *Queries) UpdateFeedFetchHints(ctx context.Context, arg UpdateFeedFetchHintsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedFetchHints,
+		arg.ID,
+		arg.TtlMinutes,
+		pq.Array(arg.SkipHours),
+		pq.Array(arg.SkipDays),
+	)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET description = $2, site_url = $3, language = $4, image_url = $5, generator = $6
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	Description sql.NullString
+	SiteUrl     sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	Generator   sql.NullString
+}
+
+// What the feed says about itself, kept up to date on every fetch
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Description,
+		arg.SiteUrl,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :one
+UPDATE feeds
+SET url = $2, updated_at = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, next_fetch_at, last_error, consecutive_failures, last_success_at, disabled_at, lease_expires_at, description, site_url, language, image_url, generator, ttl_minutes, skip_hours, skip_days
+`
+
+type UpdateFeedURLParams struct {
+	ID        uuid.UUID
+	Url       string
+	UpdatedAt time.Time
+}
+
+// Feed permanently moved, remember new URL
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedURL, arg.ID, arg.Url, arg.UpdatedAt)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -147,6 +606,22 @@ func (q *Queries) MarkFeedAsFetched(ctx context.Context, id uuid.UUID) (Feed, er
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.NextFetchAt,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.LeaseExpiresAt,
+		&i.Description,
+		&i.SiteUrl,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
 	)
 	return i, err
 }
@@ -12,13 +12,29 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	NextFetchAt         sql.NullTime
+	LastError           sql.NullString
+	ConsecutiveFailures int32
+	LastSuccessAt       sql.NullTime
+	DisabledAt          sql.NullTime
+	LeaseExpiresAt      sql.NullTime
+	Description         sql.NullString
+	SiteUrl             sql.NullString
+	Language            sql.NullString
+	ImageUrl            sql.NullString
+	Generator           sql.NullString
+	TtlMinutes          int32
+	SkipHours           []int32
+	SkipDays            []int32
 }
 
 type FeedFollow struct {
@@ -29,15 +45,65 @@ type FeedFollow struct {
 	FeedID    uuid.UUID
 }
 
+type FeedFollowFolder struct {
+	FeedFollowID uuid.UUID
+	FolderID     uuid.UUID
+}
+
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	Position  int32
+}
+
 type Post struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Title             string
+	Description       sql.NullString
+	PublishedAt       time.Time
+	Url               string
+	FeedID            uuid.UUID
+	PublishedAtSource string
+	Guid              string
+	ContentHash       string
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostRevision struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	PostID      uuid.UUID
 	Title       string
 	Description sql.NullString
 	PublishedAt time.Time
 	Url         string
-	FeedID      uuid.UUID
+	ContentHash string
+}
+
+type PostStar struct {
+	UserID            uuid.UUID
+	PostID            uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Note              sql.NullString
+	Labels            []string
+	FeedID            uuid.UUID
+	Title             string
+	Description       sql.NullString
+	Url               string
+	PublishedAt       time.Time
+	PublishedAtSource string
+	Guid              string
 }
 
 type User struct {
@@ -13,27 +13,73 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
+const adoptLegacyPostGUID = `-- name: AdoptLegacyPostGUID :one
+UPDATE posts
+SET guid = $1, updated_at = $2
+WHERE feed_id = $3 AND guid = url AND url = $4
+RETURNING id, created_at, updated_at, title, description, published_at, url, feed_id, published_at_source, guid, content_hash
+`
+
+type AdoptLegacyPostGUIDParams struct {
+	Guid      string
+	UpdatedAt time.Time
+	FeedID    uuid.UUID
+	Url       string
+}
+
+// Posts stored before GUIDs were tracked have their URL as GUID
+// Give the one with the item's link the item's real GUID instead of storing the item again
+func (q *Queries) AdoptLegacyPostGUID(ctx context.Context, arg AdoptLegacyPostGUIDParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, adoptLegacyPostGUID,
+		arg.Guid,
+		arg.UpdatedAt,
+		arg.FeedID,
+		arg.Url,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Description,
+		&i.PublishedAt,
+		&i.Url,
+		&i.FeedID,
+		&i.PublishedAtSource,
+		&i.Guid,
+		&i.ContentHash,
+	)
+	return i, err
+}
+
+const createPost = `-- name: CreatePost :execrows
 INSERT INTO posts (
-    id, created_at, updated_at, title, description, published_at, url, feed_id
+    id, created_at, updated_at, title, description, published_at, url, feed_id,
+    published_at_source, guid, content_hash
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, description, published_at, url, feed_id
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (feed_id, guid) DO NOTHING
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Description sql.NullString
-	PublishedAt time.Time
-	Url         string
-	FeedID      uuid.UUID
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Title             string
+	Description       sql.NullString
+	PublishedAt       time.Time
+	Url               string
+	FeedID            uuid.UUID
+	PublishedAtSource string
+	Guid              string
+	ContentHash       string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
+// Returns number of rows inserted, 0 means already have this post
+// Same item from same feed, nothing to do
+func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createPost,
 		arg.ID,
 		arg.CreatedAt,
 		arg.UpdatedAt,
@@ -42,7 +88,28 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.PublishedAt,
 		arg.Url,
 		arg.FeedID,
+		arg.PublishedAtSource,
+		arg.Guid,
+		arg.ContentHash,
 	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostByGUID = `-- name: GetPostByGUID :one
+SELECT id, created_at, updated_at, title, description, published_at, url, feed_id, published_at_source, guid, content_hash FROM posts WHERE feed_id = $1 AND guid = $2
+`
+
+type GetPostByGUIDParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+// Post already stored for an item, if there is one
+func (q *Queries) GetPostByGUID(ctx context.Context, arg GetPostByGUIDParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByGUID, arg.FeedID, arg.Guid)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -53,44 +120,275 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.PublishedAt,
 		&i.Url,
 		&i.FeedID,
+		&i.PublishedAtSource,
+		&i.Guid,
+		&i.ContentHash,
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.description, posts.published_at, posts.url, posts.feed_id FROM posts
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.description, posts.published_at, posts.url, posts.feed_id, posts.published_at_source, posts.guid, posts.content_hash,
+EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+) AS read,
+EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id AND post_stars.user_id = feed_follows.user_id
+) AS starred
+FROM posts
 JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2
+AND ($2::uuid IS NULL OR posts.feed_id = $2)
+AND ($3::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_folders
+    WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+    AND feed_follow_folders.folder_id = $3
+))
+AND ($4::timestamp IS NULL OR posts.published_at >= $4)
+AND ($5::timestamp IS NULL OR posts.published_at < $5)
+AND (NOT $6::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+))
+AND (
+    $7::timestamp IS NULL
+    OR (posts.published_at, posts.id) < ($7, $8::uuid)
+)
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT $9
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID            uuid.UUID
+	FeedID            uuid.NullUUID
+	FolderID          uuid.NullUUID
+	Since             sql.NullTime
+	Until             sql.NullTime
+	UnreadOnly        bool
+	CursorPublishedAt sql.NullTime
+	CursorID          uuid.NullUUID
+	PageSize          int32
+}
+
+type GetPostsForUserRow struct {
+	Post    Post
+	Read    bool
+	Starred bool
 }
 
+// Whether this user has read the post
+// Whether this user starred the post
 // Know what feed every post in the database belongs to
 // Tells us which feeds User is following
+// Optional filters, null means don't filter
+// Cursor is the last post of previous page, carry on after it
+// id breaks ties between posts published at the same time
 // Newest stuff first
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.Since,
+		arg.Until,
+		arg.UnreadOnly,
+		arg.CursorPublishedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForUserRow
+	for rows.Next() {
+		var i GetPostsForUserRow
+		if err := rows.Scan(
+			&i.Post.ID,
+			&i.Post.CreatedAt,
+			&i.Post.UpdatedAt,
+			&i.Post.Title,
+			&i.Post.Description,
+			&i.Post.PublishedAt,
+			&i.Post.Url,
+			&i.Post.FeedID,
+			&i.Post.PublishedAtSource,
+			&i.Post.Guid,
+			&i.Post.ContentHash,
+			&i.Read,
+			&i.Starred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPostDates = `-- name: GetRecentPostDates :many
+SELECT published_at FROM posts
+WHERE feed_id = $1 AND published_at_source <> 'fetched'
+ORDER BY published_at DESC
+LIMIT $2
+`
+
+type GetRecentPostDatesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+// Used to work out how often a feed publishes
+// Fetch time fallbacks say nothing about publishing frequency
+func (q *Queries) GetRecentPostDates(ctx context.Context, arg GetRecentPostDatesParams) ([]time.Time, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostDates, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var published_at time.Time
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = $1
+WHERE feed_id = $2
+AND guid NOT IN (
+    SELECT guid FROM posts WHERE feed_id = $1
+)
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+// Merging feeds, move posts from one feed to another
+// Posts the other feed already has are left behind
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const searchPostsForUser = `-- name: SearchPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.description, posts.published_at, posts.url, posts.feed_id, posts.published_at_source, posts.guid, posts.content_hash,
+EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+) AS read,
+EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id AND post_stars.user_id = feed_follows.user_id
+) AS starred,
+ts_rank(
+    posts_search_vector(posts.title, posts.description),
+    websearch_to_tsquery('english', $1)
+)::real AS rank,
+ts_headline(
+    'english', html_escape(posts.title), websearch_to_tsquery('english', $1),
+    'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'
+)::text AS title_highlight,
+ts_headline(
+    'english', html_escape(regexp_replace(coalesce(posts.description, ''), '<[^>]*>', ' ', 'g')),
+    websearch_to_tsquery('english', $1),
+    'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10'
+)::text AS snippet
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $2
+AND posts_search_vector(posts.title, posts.description) @@ websearch_to_tsquery('english', $1)
+AND ($3::uuid IS NULL OR posts.feed_id = $3)
+AND (
+    $4::real IS NULL
+    OR (
+        ts_rank(
+            posts_search_vector(posts.title, posts.description),
+            websearch_to_tsquery('english', $1)
+        )::real,
+        posts.id
+    ) < ($4, $5::uuid)
+)
+ORDER BY rank DESC, posts.id DESC
+LIMIT $6
+`
+
+type SearchPostsForUserParams struct {
+	Query      string
+	UserID     uuid.UUID
+	FeedID     uuid.NullUUID
+	CursorRank sql.NullFloat64
+	CursorID   uuid.NullUUID
+	PageSize   int32
+}
+
+type SearchPostsForUserRow struct {
+	Post           Post
+	Read           bool
+	Starred        bool
+	Rank           float32
+	TitleHighlight string
+	Snippet        string
+}
+
+// Full-text search over posts in feeds the user follows, best matches first
+// websearch_to_tsquery understands "quoted phrases", or and -excluded words
+// Matching words wrapped in <mark>, rest of the text is HTML-escaped so it's safe to render
+// Tags stripped from description first so snippets don't cut them in half
+// Cursor is the last result of previous page
+func (q *Queries) SearchPostsForUser(ctx context.Context, arg SearchPostsForUserParams) ([]SearchPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsForUser,
+		arg.Query,
+		arg.UserID,
+		arg.FeedID,
+		arg.CursorRank,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []SearchPostsForUserRow
 	for rows.Next() {
-		var i Post
+		var i SearchPostsForUserRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.Title,
-			&i.Description,
-			&i.PublishedAt,
-			&i.Url,
-			&i.FeedID,
+			&i.Post.ID,
+			&i.Post.CreatedAt,
+			&i.Post.UpdatedAt,
+			&i.Post.Title,
+			&i.Post.Description,
+			&i.Post.PublishedAt,
+			&i.Post.Url,
+			&i.Post.FeedID,
+			&i.Post.PublishedAtSource,
+			&i.Post.Guid,
+			&i.Post.ContentHash,
+			&i.Read,
+			&i.Starred,
+			&i.Rank,
+			&i.TitleHighlight,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
@@ -104,3 +402,68 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const setPostContentHash = `-- name: SetPostContentHash :exec
+UPDATE posts
+SET content_hash = $2
+WHERE id = $1
+`
+
+type SetPostContentHashParams struct {
+	ID          uuid.UUID
+	ContentHash string
+}
+
+// Posts stored before content was hashed, nothing changed so updated_at stays
+func (q *Queries) SetPostContentHash(ctx context.Context, arg SetPostContentHashParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContentHash, arg.ID, arg.ContentHash)
+	return err
+}
+
+const updatePost = `-- name: UpdatePost :one
+UPDATE posts
+SET title = $2, description = $3, published_at = $4, url = $5,
+    published_at_source = $6, content_hash = $7, updated_at = $8
+WHERE id = $1
+RETURNING id, created_at, updated_at, title, description, published_at, url, feed_id, published_at_source, guid, content_hash
+`
+
+type UpdatePostParams struct {
+	ID                uuid.UUID
+	Title             string
+	Description       sql.NullString
+	PublishedAt       time.Time
+	Url               string
+	PublishedAtSource string
+	ContentHash       string
+	UpdatedAt         time.Time
+}
+
+// Publisher changed the item, overwrite with latest version
+func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, updatePost,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.PublishedAt,
+		arg.Url,
+		arg.PublishedAtSource,
+		arg.ContentHash,
+		arg.UpdatedAt,
+	)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Description,
+		&i.PublishedAt,
+		&i.Url,
+		&i.FeedID,
+		&i.PublishedAtSource,
+		&i.Guid,
+		&i.ContentHash,
+	)
+	return i, err
+}
@@ -0,0 +1,298 @@
+// Apply and roll back goose migrations from sql/schema without needing the goose tool installed
+// Uses goose's own version table so databases migrated by hand with goose carry on working
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one file from sql/schema, e.g. 001_users.sql is version 1
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads every .sql file in fsys and splits it into its Up and Down sections
+// Sorted by version, oldest first
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		// Version is everything before first underscore
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s has no version prefix", entry.Name())
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has invalid version: %w", entry.Name(), err)
+		}
+
+		dat, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		up, down, err := splitSections(string(dat))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    entry.Name(),
+			Up:      up,
+			Down:    down,
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", migrations[i-1].Name, migrations[i].Name)
+		}
+	}
+	return migrations, nil
+}
+
+// Everything between "-- +goose Up" and "-- +goose Down" is Up, everything after is Down
+func splitSections(contents string) (string, string, error) {
+	_, rest, found := strings.Cut(contents, "-- +goose Up")
+	if !found {
+		return "", "", fmt.Errorf("missing -- +goose Up annotation")
+	}
+	up, down, _ := strings.Cut(rest, "-- +goose Down")
+	return strings.TrimSpace(up), strings.TrimSpace(down), nil
+}
+
+// Same table goose creates
+const createVersionTable = `
+CREATE TABLE IF NOT EXISTS goose_db_version (
+    id SERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP NULL DEFAULT NOW()
+)`
+
+// Held while changing schema so several instances starting at once don't migrate at the same time
+// Arbitrary, just has to be the same everywhere
+const advisoryLockID = 7_140_512_113
+
+// Returned by Check
+var (
+	ErrDatabaseAhead = errors.New("database has migrations this binary doesn't know about")
+	ErrPending       = errors.New("database has pending migrations")
+)
+
+// Status of one migration in the database
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Versions currently applied to the database, and when
+// goose records rollbacks either by deleting the row or adding one with is_applied false
+// so only the latest row for each version counts
+func appliedVersions(ctx context.Context, db querier) (map[int64]time.Time, error) {
+	_, err := db.ExecContext(ctx, createVersionTable)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+SELECT DISTINCT ON (version_id) version_id, is_applied, tstamp FROM goose_db_version
+ORDER BY version_id, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var tstamp sql.NullTime
+		if err := rows.Scan(&version, &isApplied, &tstamp); err != nil {
+			return nil, err
+		}
+		// goose inserts version 0 when it creates the table, not a real migration
+		if isApplied && version > 0 {
+			applied[version] = tstamp.Time
+		}
+	}
+	return applied, rows.Err()
+}
+
+// Both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Up applies every migration that hasn't been applied yet, oldest first
+// Each migration runs in its own transaction along with its version row
+// Returns the migrations that were applied
+func Up(ctx context.Context, db *sql.DB, migrations []Migration) ([]Migration, error) {
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	ran := []Migration{}
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		done, err := run(ctx, db, migration, true)
+		if err != nil {
+			return ran, fmt.Errorf("applying %s: %w", migration.Name, err)
+		}
+		if done {
+			ran = append(ran, migration)
+		}
+	}
+	return ran, nil
+}
+
+// Down rolls back the most recently applied migration
+// Returns false if nothing was applied
+func Down(ctx context.Context, db *sql.DB, migrations []Migration) (Migration, bool, error) {
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return Migration{}, false, err
+	}
+
+	latest := int64(0)
+	for version := range applied {
+		latest = max(latest, version)
+	}
+	if latest == 0 {
+		return Migration{}, false, nil
+	}
+
+	for _, migration := range migrations {
+		if migration.Version != latest {
+			continue
+		}
+		done, err := run(ctx, db, migration, false)
+		if err != nil {
+			return migration, false, fmt.Errorf("rolling back %s: %w", migration.Name, err)
+		}
+		return migration, done, nil
+	}
+	return Migration{}, false, fmt.Errorf("can't roll back version %d: %w", latest, ErrDatabaseAhead)
+}
+
+// Every known migration and whether it has been applied
+func GetStatus(ctx context.Context, db *sql.DB, migrations []Migration) ([]Status, error) {
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []Status{}
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Check database schema matches migrations
+// ErrDatabaseAhead if database has versions newer than any we know, e.g. after rolling back a deploy
+// ErrPending if some migrations haven't been applied yet
+func Check(ctx context.Context, db *sql.DB, migrations []Migration) error {
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	latest := int64(0)
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: database is at version %d, latest migration is %d", ErrDatabaseAhead, version, latest)
+		}
+	}
+
+	pending := []string{}
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration.Name)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %s", ErrPending, strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// Apply (up) or roll back (down) one migration in a transaction along with its version row
+// Takes the advisory lock and checks the version again first, so if another instance got there
+// while we were waiting this does nothing and returns false
+func run(ctx context.Context, db *sql.DB, migration Migration, up bool) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	// No-op once committed
+	defer tx.Rollback()
+
+	// Released when transaction ends
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, advisoryLockID)
+	if err != nil {
+		return false, err
+	}
+	applied, err := appliedVersions(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := applied[migration.Version]; ok == up {
+		return false, nil
+	}
+
+	statements := migration.Down
+	record := `DELETE FROM goose_db_version WHERE version_id = $1`
+	if up {
+		statements = migration.Up
+		record = `INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, true)`
+	}
+
+	// No parameters, so lib/pq sends it as a simple query and multiple statements are fine
+	if statements != "" {
+		_, err = tx.ExecContext(ctx, statements)
+		if err != nil {
+			return false, err
+		}
+	}
+	_, err = tx.ExecContext(ctx, record, migration.Version)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
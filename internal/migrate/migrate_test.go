@@ -0,0 +1,84 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitSectionsDollarQuotedBody(t *testing.T) {
+	contents := `-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION html_escape(value TEXT) RETURNS TEXT AS $$
+    -- semicolons and comments inside the body belong to the function
+    SELECT replace(replace(value, '&', '&amp;'), '<', '&lt;');
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+CREATE INDEX posts_escaped_idx ON posts (html_escape(title));
+
+-- +goose Down
+DROP INDEX posts_escaped_idx;
+DROP FUNCTION html_escape;
+`
+	up, down, err := splitSections(contents)
+	if err != nil {
+		t.Fatalf("splitSections returned error: %v", err)
+	}
+
+	wantBody := `AS $$
+    -- semicolons and comments inside the body belong to the function
+    SELECT replace(replace(value, '&', '&amp;'), '<', '&lt;');
+$$ LANGUAGE SQL IMMUTABLE;`
+	if !strings.Contains(up, wantBody) {
+		t.Errorf("up section lost the function body:\n%s", up)
+	}
+	if !strings.HasSuffix(up, "CREATE INDEX posts_escaped_idx ON posts (html_escape(title));") {
+		t.Errorf("up section doesn't end with the statement after the function:\n%s", up)
+	}
+	if strings.Contains(up, "DROP") {
+		t.Errorf("up section includes down statements:\n%s", up)
+	}
+	if down != "DROP INDEX posts_escaped_idx;\nDROP FUNCTION html_escape;" {
+		t.Errorf("down = %q", down)
+	}
+}
+
+func TestSplitSections(t *testing.T) {
+	up, down, err := splitSections("-- +goose Up\nCREATE TABLE a (id INT);\n")
+	if err != nil || up != "CREATE TABLE a (id INT);" || down != "" {
+		t.Errorf("splitSections() without down = %q, %q, %v", up, down, err)
+	}
+
+	_, _, err = splitSections("CREATE TABLE a (id INT);\n-- +goose Down\nDROP TABLE a;\n")
+	if err == nil {
+		t.Error("splitSections() accepted a file without -- +goose Up")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_b.sql":   {Data: []byte("-- +goose Up\nSELECT 10;\n-- +goose Down\nSELECT -10;\n")},
+		"002_a.sql":   {Data: []byte("-- +goose Up\nSELECT 2;\n")},
+		"README.md":   {Data: []byte("not a migration")},
+		"sub/3_c.sql": {Data: []byte("-- +goose Up\nSELECT 3;\n")},
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations, want 2: %+v", len(migrations), migrations)
+	}
+	if migrations[0].Version != 2 || migrations[0].Name != "002_a.sql" || migrations[0].Up != "SELECT 2;" {
+		t.Errorf("first migration = %+v", migrations[0])
+	}
+	if migrations[1].Version != 10 || migrations[1].Down != "SELECT -10;" {
+		t.Errorf("second migration = %+v", migrations[1])
+	}
+
+	fsys["2_again.sql"] = &fstest.MapFile{Data: []byte("-- +goose Up\nSELECT 2;\n")}
+	if _, err := Load(fsys); err == nil {
+		t.Error("Load accepted two migrations with the same version")
+	}
+}
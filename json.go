@@ -24,9 +24,8 @@ func respondWithError(w http.ResponseWriter, code int, msg string) {
 	// Take struct and add JSON tags to specify how we want to unmarshal, convert struct into JSON object
 	type errResponse struct {
 		// Struct has 1 field, Error
-		// Add this JSON tag to say this key should marshal to error
-		// Saying I have error field, want key for field to be error
-		Error string `json:"error`
+		// Key has always been sent as Error, clients depend on it, so the tag spells that out
+		Error string `json:"Error"`
 	}
 
 	respondWithJSON(w, code, errResponse{
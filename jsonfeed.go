@@ -0,0 +1,72 @@
+package main
+
+import "strings"
+
+// JSON Feed 1.1, https://www.jsonfeed.org/version/1.1/
+// Served as application/feed+json
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Language    string         `json:"language"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	// id is required and is the item's unique identifier
+	ID            string `json:"id"`
+	URL           string `json:"url"`
+	ExternalURL   string `json:"external_url"`
+	Title         string `json:"title"`
+	Summary       string `json:"summary"`
+	ContentHTML   string `json:"content_html"`
+	ContentText   string `json:"content_text"`
+	DatePublished string `json:"date_published"`
+	DateModified  string `json:"date_modified"`
+}
+
+// Map into RSSFeed so scrapeFeed only deals with one shape
+func (jsonFeed JSONFeed) toRSSFeed() RSSFeed {
+	rssFeed := RSSFeed{
+		Channel: RSSChannel{
+			Title:       strings.TrimSpace(jsonFeed.Title),
+			Link:        strings.TrimSpace(jsonFeed.HomePageURL),
+			Description: strings.TrimSpace(jsonFeed.Description),
+			Language:    strings.TrimSpace(jsonFeed.Language),
+			Image:       RSSImage{URL: firstNonEmpty(jsonFeed.Icon, jsonFeed.Favicon)},
+		},
+	}
+
+	for _, item := range jsonFeed.Items {
+		// url is optional, external_url points at linked article for link blogs
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		description := firstNonEmpty(item.Summary, item.ContentHTML, item.ContentText)
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			Title:       strings.TrimSpace(item.Title),
+			Link:        strings.TrimSpace(link),
+			Description: description,
+			PubDate:     strings.TrimSpace(item.DatePublished),
+			Updated:     strings.TrimSpace(item.DateModified),
+			GUID:        strings.TrimSpace(item.ID),
+		})
+	}
+	return rssFeed
+}
+
+// Returns first value that isn't blank, trimmed
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFeedJSONFeed(t *testing.T) {
+	feed := `{
+		"version": "https://jsonfeed.org/version/1.1",
+		"title": "Links",
+		"home_page_url": "https://example.net/",
+		"description": "Things worth reading",
+		"language": "en-GB",
+		"favicon": "https://example.net/favicon.ico",
+		"items": [
+			{
+				"id": "1",
+				"url": "https://example.net/1",
+				"title": "Own post",
+				"content_html": "<p>Body</p>",
+				"date_published": "2024-01-02T03:04:05Z",
+				"date_modified": "2024-01-03T03:04:05Z"
+			},
+			{
+				"id": "2",
+				"external_url": "https://elsewhere.example/article",
+				"title": "Linked post",
+				"summary": "Worth a read",
+				"content_text": "Full text"
+			}
+		]
+	}`
+
+	// Sniffed from the body, not the content type
+	rssFeed, err := parseFeed("text/plain", []byte(feed))
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+	channel := rssFeed.Channel
+	if channel.Title != "Links" || channel.Link != "https://example.net/" || channel.Description != "Things worth reading" {
+		t.Errorf("channel = %q, %q, %q", channel.Title, channel.Link, channel.Description)
+	}
+	// No icon, so favicon
+	if channel.Language != "en-GB" || channel.imageURL() != "https://example.net/favicon.ico" {
+		t.Errorf("channel language, image = %q, %q", channel.Language, channel.imageURL())
+	}
+	if len(channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(channel.Item))
+	}
+
+	first := channel.Item[0]
+	if first.GUID != "1" || first.Link != "https://example.net/1" || first.Description != "<p>Body</p>" {
+		t.Errorf("first item guid, link, description = %q, %q, %q", first.GUID, first.Link, first.Description)
+	}
+	if first.PubDate != "2024-01-02T03:04:05Z" || first.Updated != "2024-01-03T03:04:05Z" {
+		t.Errorf("first item dates = %q, %q", first.PubDate, first.Updated)
+	}
+
+	// Link blogs only have external_url, summary wins over content
+	second := channel.Item[1]
+	if second.Link != "https://elsewhere.example/article" || second.Description != "Worth a read" {
+		t.Errorf("second item link, description = %q, %q", second.Link, second.Description)
+	}
+}
+
+func TestParseFeedJSONFeedVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantErr     error
+	}{
+		{
+			name:        "version 1",
+			contentType: "application/json",
+			body:        `{"version": "https://jsonfeed.org/version/1", "title": "Old", "items": []}`,
+		},
+		{
+			name:        "version 1.1",
+			contentType: "application/feed+json; charset=utf-8",
+			body:        `{"version": "https://jsonfeed.org/version/1.1", "title": "New", "items": []}`,
+		},
+		{
+			name:        "JSON API response",
+			contentType: "application/json",
+			body:        `{"status": "ok", "items": [{"id": "1"}]}`,
+			wantErr:     errNotAFeed,
+		},
+		{
+			name:        "unknown version",
+			contentType: "application/json",
+			body:        `{"version": "2.0", "title": "Not it"}`,
+			wantErr:     errNotAFeed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFeed(tt.contentType, []byte(tt.body))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("parseFeed() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
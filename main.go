@@ -1,15 +1,14 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
-	"github.com/go-chi/chi"
-	"github.com/go-chi/cors"
 	"github.com/jakeleesh/rssagg/internal/database"
 	"github.com/joho/godotenv"
 
@@ -22,126 +21,81 @@ import (
 type apiConfig struct {
 	// Exposed by code generated using sqlc
 	DB *database.Queries
+	// Raw connection, only needed to start transactions
+	Conn *sql.DB
+	// How many posts GET /v1/posts returns
+	PostsLimit int32
+	// Fetches feeds on demand, e.g. POST /v1/feeds/{feedID}/refresh
+	Scraper *scraper
+}
+
+// Subcommands the binary understands
+// Running with no subcommand is the same as serve, how it always worked
+var commands = map[string]func(args []string){
+	"serve":       runServe,
+	"scrape":      runScrape,
+	"scrape-once": runScrapeOnce,
+	"migrate":     runMigrate,
+	"create-user": runCreateUser,
 }
 
 func main() {
 	// Environment doesn't exist in current shell session
 	// Use package to grab environment variables
+	// Loaded before anything reads config so .env works like real environment variables
 	godotenv.Load(".env")
 
-	// Read PORT variable by key
-	portString := os.Getenv("PORT")
-	if portString == "" {
-		// log.Fatal will exit program immediately with Error Code 1 and message
-		log.Fatal("PORT is not found in the environment")
-	}
-
-	// Import database connection
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		log.Fatal("DB_URL is not found in environment")
-	}
-
-	// Connect to database
-	// Go standard library has built-in SQL package
-	conn, err := sql.Open("postgres", dbURL)
-	if err != nil {
-		log.Fatal("Can't connect to database:", err)
+	if len(os.Args) < 2 {
+		runServe(nil)
+		return
 	}
 
-	db := database.New(conn)
-	// New API Config
-	// Can pass into our handlers so that they have access to database
-	apiCfg := apiConfig{
-		// Takes in database.queries
-		// Have sql.db so need to convert into a connection
-		DB: db,
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
 	}
+	// Each command parses its own flags
+	command(os.Args[2:])
+}
 
-	// Hook up startScraping to main function
-	// Call before ListenAndServe() because server blocks and waits forever for incoming requests
-	// Call it on a new goroutine so doesn't interrupt main
-	// because startScraping is never going to return, it's long running functio, infinite for loop
-	go startScraping(db, 10, time.Minute)
-
-	// Spin up Server
-	// New Router Object
-	router := chi.NewRouter()
-
-	// cors configuration from cors package installed
-	// Essentially telling Server to send extra HTTP Headers, tell browsers allow to use these
-	router.Use(
-		cors.Handler(
-			cors.Options{
-				// Allow send requests to http or https
-				AllowedOrigins: []string{"https://*", "http://*"},
-				// Allow methods
-				AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-				// Allow send any Headers
-				AllowedHeaders:   []string{"*"},
-				ExposedHeaders:   []string{"Link"},
-				AllowCredentials: false,
-				MaxAge:           300,
-			},
-		),
-	)
-
-	// Create new Router
-	v1Router := chi.NewRouter()
-	// Hook up HTTP Handler to a specific HTTP method and path
-	// Handle /healthz path with handlerReadiness function
-	// Name healthz, kubernetes standard to see if server is live and running
-	// POST request get 200 not intention
-	// healthz endpoint should only be accessible by GET request
-	// Rather than using v1Router.handleFunc, use v1Router.Get. Scope hanlder to only fire on GET requests.
-	v1Router.Get("/healthz", handlerReadiness)
-	// Hook up error handler
-	v1Router.Get("/err", handlerErr)
-	// Hook up createUser Handler
-	// Be POST Request
-	v1Router.Post("/users", apiCfg.handlerCreateUser)
-	// Hook up GetUser Handler to GET HTTP method
-	// Same path, different method
-	// Call middlewareAuth to convert GetUser Handler into standard HTTP Handler
-	// Calling middlewareAuth to get authenticated user and then calling back the GetUser Handler
-	v1Router.Get("/users", apiCfg.middlewareAuth(apiCfg.handleGetUser))
-
-	// Creating a resouce, use POST
-	v1Router.Post("/feeds", apiCfg.middlewareAuth((apiCfg.handlerCreateFeed)))
-	v1Router.Get("/feeds", apiCfg.handlerGetFeeds)
-
-	v1Router.Get("/posts", apiCfg.middlewareAuth(apiCfg.handlerGetPostsForUser))
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: rssagg <command> [flags]
 
-	v1Router.Post("/feed_follows", apiCfg.middlewareAuth(apiCfg.handlerCreateFeedFollow))
-	v1Router.Get("/feed_follows", apiCfg.middlewareAuth(apiCfg.handlerGetFeedFollows))
-	// Authenticated
-	// Need feedFollowID and DELETE request
-	// HTTP DELETE request don't typically have body
-	// More conventional to pass ID in path
-	v1Router.Delete("/feed_follows/{feedFollowID}", apiCfg.middlewareAuth(apiCfg.handlerDeleteFeedFollow))
+Commands:
+  serve         Run the API server, and the scraper unless -scraper=false
+  scrape        Run only the scraper worker loop
+  scrape-once   Fetch a single feed now: scrape-once -feed <id>
+  migrate       Manage database schema: migrate up|down|status
+  create-user   Create a user and print its API key: create-user -name <name>
 
-	// Create v1Router is because going to mount
-	// Nesting v1Router under /v1 path
-	// Full path for request will be: /v1/healthz
-	// So that if make changes in future, can have 2 handlers, v1 and v2 for API. Standard practie.
-	router.Mount("/v1", v1Router)
+Run rssagg <command> -h for the flags of a command
+Every command also takes -config <file> and the config flags, see README
+`)
+}
 
-	// Connect Router to HTTP Server
-	srv := &http.Server{
-		Handler: router,
-		Addr:    ":" + portString,
+// Connect to database
+// Go standard library has built-in SQL package
+func openDatabase(cfg config) *sql.DB {
+	conn, err := sql.Open("postgres", cfg.DBURL)
+	if err != nil {
+		log.Fatal("Can't connect to database:", err)
 	}
+	return conn
+}
 
-	log.Printf("Server starting on port %v", portString)
-	// Returns an Error
-	// ListenAndServe will block, just stop and starts handling HTTP Requests
-	// Nothing SHOULD be returned, Server should run forever
-	err = srv.ListenAndServe()
-	// Anything goes wrong in process of handling requests, error returned
-	if err != nil {
-		// Log and exit program
-		log.Fatal(err)
+// Scraper set up the same way whichever command runs it
+func newScraper(conn *sql.DB, cfg config) *scraper {
+	return &scraper{
+		DB:           database.New(conn),
+		conn:         conn,
+		maxFailures:  cfg.Scraper.MaxFailures,
+		fetchTimeout: cfg.Scraper.FetchTimeout,
 	}
+}
 
-	fmt.Println("Port:", portString)
+// Root context, cancelled when we get SIGINT (Ctrl+C) or SIGTERM (deploys)
+// Everything long running watches it so we can shut down cleanly
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 }
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"database/sql"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,7 +21,7 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Name      string    `json:"name"`
-	APIKey    string    `json:"api_key`
+	APIKey    string    `json:"APIKey"` // Sent as APIKey since the start, existing clients look for it
 }
 
 // All this does is return a new User struct where populate with stuff from database User
@@ -40,18 +43,43 @@ type Feed struct {
 	Name      string    `json:"name"`
 	Url       string    `json:"url"`
 	UserID    uuid.UUID `json:"user_id"`
+	// From the feed itself, updated every fetch, null when feed doesn't say
+	Description *string `json:"description"`
+	SiteURL     *string `json:"site_url"`
+	Language    *string `json:"language"`
+	ImageURL    *string `json:"image_url"`
+	Generator   *string `json:"generator"`
+	// Fetch status, lets clients see feeds that are broken
+	// Null when never happened
+	LastFetchedAt       *time.Time `json:"last_fetched_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	NextFetchAt         *time.Time `json:"next_fetch_at"`
+	LastError           *string    `json:"last_error"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
 }
 
 // Gives more control in code not generated by sqlc
 // Able to define the shape of response
 func databaseFeedToFeed(dbFeed database.Feed) Feed {
 	return Feed{
-		ID:        dbFeed.ID,
-		CreatedAt: dbFeed.CreatedAt,
-		UpdatedAt: dbFeed.UpdatedAt,
-		Name:      dbFeed.Name,
-		Url:       dbFeed.Url,
-		UserID:    dbFeed.UserID,
+		ID:                  dbFeed.ID,
+		CreatedAt:           dbFeed.CreatedAt,
+		UpdatedAt:           dbFeed.UpdatedAt,
+		Name:                dbFeed.Name,
+		Url:                 dbFeed.Url,
+		UserID:              dbFeed.UserID,
+		Description:         nullStringToStringPtr(dbFeed.Description),
+		SiteURL:             nullStringToStringPtr(dbFeed.SiteUrl),
+		Language:            nullStringToStringPtr(dbFeed.Language),
+		ImageURL:            nullStringToStringPtr(dbFeed.ImageUrl),
+		Generator:           nullStringToStringPtr(dbFeed.Generator),
+		LastFetchedAt:       nullTimeToTimePtr(dbFeed.LastFetchedAt),
+		LastSuccessAt:       nullTimeToTimePtr(dbFeed.LastSuccessAt),
+		NextFetchAt:         nullTimeToTimePtr(dbFeed.NextFetchAt),
+		LastError:           nullStringToStringPtr(dbFeed.LastError),
+		ConsecutiveFailures: dbFeed.ConsecutiveFailures,
+		DisabledAt:          nullTimeToTimePtr(dbFeed.DisabledAt),
 	}
 }
 
@@ -64,12 +92,73 @@ func databaseFeedstoFeeds(dbFeeds []database.Feed) []Feed {
 	return feeds
 }
 
+// What a URL's feed looks like, from fetching it without storing anything
+type FeedPreview struct {
+	URL         string            `json:"url"`
+	Title       string            `json:"title"`
+	Link        string            `json:"link"`
+	Description string            `json:"description"`
+	Language    string            `json:"language"`
+	ImageURL    string            `json:"image_url"`
+	Generator   string            `json:"generator"`
+	Items       []FeedPreviewItem `json:"items"`
+}
+
+type FeedPreviewItem struct {
+	Title       string    `json:"title"`
+	Link        string    `json:"link"`
+	Description *string   `json:"description"`
+	PublishedAt time.Time `json:"published_at"`
+	GUID        string    `json:"guid"`
+}
+
+// Newest items first, at most maxItems of them
+// Dates are worked out the same way the scraper does
+func rssFeedToFeedPreview(url string, rssFeed RSSFeed, maxItems int) FeedPreview {
+	channel := rssFeed.Channel
+	preview := FeedPreview{
+		URL:         url,
+		Title:       strings.TrimSpace(channel.Title),
+		Link:        strings.TrimSpace(channel.Link),
+		Description: strings.TrimSpace(channel.Description),
+		Language:    strings.TrimSpace(channel.Language),
+		ImageURL:    channel.imageURL(),
+		Generator:   strings.TrimSpace(channel.Generator),
+		Items:       []FeedPreviewItem{},
+	}
+	fetchedAt := time.Now().UTC()
+	for _, item := range channel.Item {
+		pubAt, _ := itemPublishedAt(item, fetchedAt)
+		link := item.Link
+		if link == "" && isPermalink(item.GUID) {
+			link = item.GUID
+		}
+		preview.Items = append(preview.Items, FeedPreviewItem{
+			Title:       item.Title,
+			Link:        link,
+			Description: nullStringToStringPtr(nullString(item.Description)),
+			PublishedAt: pubAt,
+			GUID:        itemGUID(item),
+		})
+	}
+	sort.SliceStable(preview.Items, func(i, j int) bool {
+		return preview.Items[i].PublishedAt.After(preview.Items[j].PublishedAt)
+	})
+	if len(preview.Items) > maxItems {
+		preview.Items = preview.Items[:maxItems]
+	}
+	return preview
+}
+
 type FeedFollow struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	UserID    uuid.UUID `json:"user_id"`
 	FeedID    uuid.UUID `json:"feed_id"`
+	// Only known when listing follows, left out otherwise
+	FolderIDs   []uuid.UUID `json:"folder_ids,omitzero"`
+	UnreadCount *int64      `json:"unread_count,omitempty"`
 }
 
 func databaseFeedFollowToFeedFollow(dbFeedFollow database.FeedFollow) FeedFollow {
@@ -82,10 +171,17 @@ func databaseFeedFollowToFeedFollow(dbFeedFollow database.FeedFollow) FeedFollow
 	}
 }
 
-func databaseFeedFollowstoFeedFollows(dbFeedFollows []database.FeedFollow) []FeedFollow {
+func databaseFeedFollowstoFeedFollows(dbFeedFollows []database.GetFeedFollowsRow) []FeedFollow {
 	feedFollows := []FeedFollow{}
 	for _, dbFeedFollows := range dbFeedFollows {
-		feedFollows = append(feedFollows, databaseFeedFollowToFeedFollow(dbFeedFollows))
+		feedFollow := databaseFeedFollowToFeedFollow(dbFeedFollows.FeedFollow)
+		// Always a list when listing, even if not in any folder
+		feedFollow.FolderIDs = dbFeedFollows.FolderIds
+		if feedFollow.FolderIDs == nil {
+			feedFollow.FolderIDs = []uuid.UUID{}
+		}
+		feedFollow.UnreadCount = &dbFeedFollows.UnreadCount
+		feedFollows = append(feedFollows, feedFollow)
 	}
 	return feedFollows
 }
@@ -104,6 +200,15 @@ type Post struct {
 	PublishedAt time.Time `json:"published_at"`
 	Url         string    `json:"url"`
 	FeedID      uuid.UUID `json:"feed_id"`
+	// published, dc_date, updated or fetched, tells clients when published_at is a guess
+	PublishedAtSource string `json:"published_at_source"`
+	// Identifies the item within its feed, publisher's GUID or link
+	GUID string `json:"guid"`
+	// Whether the user asking has read or starred it
+	Read    bool `json:"read"`
+	Starred bool `json:"starred"`
+	// Note and labels, only included when listing starred posts
+	Star *PostStar `json:"star,omitempty"`
 }
 
 func databasePostToPost(dbPost database.Post) Post {
@@ -113,21 +218,198 @@ func databasePostToPost(dbPost database.Post) Post {
 	}
 
 	return Post{
-		ID:          dbPost.ID,
-		CreatedAt:   dbPost.CreatedAt,
-		UpdatedAt:   dbPost.UpdatedAt,
-		Title:       dbPost.Title,
-		Description: description,
-		PublishedAt: dbPost.PublishedAt,
-		Url:         dbPost.Url,
-		FeedID:      dbPost.FeedID,
+		ID:                dbPost.ID,
+		CreatedAt:         dbPost.CreatedAt,
+		UpdatedAt:         dbPost.UpdatedAt,
+		Title:             dbPost.Title,
+		Description:       description,
+		PublishedAt:       dbPost.PublishedAt,
+		Url:               dbPost.Url,
+		FeedID:            dbPost.FeedID,
+		PublishedAtSource: dbPost.PublishedAtSource,
+		GUID:              dbPost.Guid,
 	}
 }
 
-func databasePostsToPosts(dbPosts []database.Post) []Post {
+// Posts listed for a user come with that user's read state
+func databasePostsToPosts(dbPosts []database.GetPostsForUserRow) []Post {
 	posts := []Post{}
 	for _, dbPost := range dbPosts {
-		posts = append(posts, databasePostToPost(dbPost))
+		post := databasePostToPost(dbPost.Post)
+		post.Read = dbPost.Read
+		post.Starred = dbPost.Starred
+		posts = append(posts, post)
 	}
 	return posts
 }
+
+type PostStar struct {
+	PostID    uuid.UUID `json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Note      *string   `json:"note"`
+	Labels    []string  `json:"labels"`
+	// Post was deleted, e.g. with its feed, and is shown as it was when starred
+	PostDeleted bool `json:"post_deleted"`
+}
+
+func databasePostStarToPostStar(dbStar database.PostStar) PostStar {
+	labels := dbStar.Labels
+	// Always a list in JSON, never null
+	if labels == nil {
+		labels = []string{}
+	}
+	return PostStar{
+		PostID:    dbStar.PostID,
+		CreatedAt: dbStar.CreatedAt,
+		UpdatedAt: dbStar.UpdatedAt,
+		Note:      nullStringToStringPtr(dbStar.Note),
+		Labels:    labels,
+	}
+}
+
+func databaseStarredPostsToPosts(dbPosts []database.GetStarredPostsForUserRow) []Post {
+	posts := []Post{}
+	for _, dbPost := range dbPosts {
+		post := databasePostToPost(database.Post{
+			ID:                dbPost.PostStar.PostID,
+			CreatedAt:         dbPost.PostCreatedAt,
+			UpdatedAt:         dbPost.PostUpdatedAt,
+			Title:             dbPost.Title,
+			Description:       dbPost.Description,
+			PublishedAt:       dbPost.PublishedAt,
+			Url:               dbPost.Url,
+			FeedID:            dbPost.FeedID,
+			PublishedAtSource: dbPost.PublishedAtSource,
+			Guid:              dbPost.Guid,
+		})
+		star := databasePostStarToPostStar(dbPost.PostStar)
+		star.PostDeleted = dbPost.PostDeleted
+		post.Read = dbPost.Read
+		post.Starred = true
+		post.Star = &star
+		posts = append(posts, post)
+	}
+	return posts
+}
+
+// Post plus how well and where it matched a search
+type PostSearchResult struct {
+	Post
+	Rank float32 `json:"rank"`
+	// Title and part of description with matching words wrapped in <mark>
+	TitleHighlight string `json:"title_highlight"`
+	Snippet        string `json:"snippet"`
+}
+
+func databaseSearchResultsToSearchResults(dbResults []database.SearchPostsForUserRow) []PostSearchResult {
+	results := []PostSearchResult{}
+	for _, dbResult := range dbResults {
+		post := databasePostToPost(dbResult.Post)
+		post.Read = dbResult.Read
+		post.Starred = dbResult.Starred
+		results = append(results, PostSearchResult{
+			Post:           post,
+			Rank:           dbResult.Rank,
+			TitleHighlight: dbResult.TitleHighlight,
+			Snippet:        dbResult.Snippet,
+		})
+	}
+	return results
+}
+
+type UnreadCount struct {
+	FeedID      uuid.UUID `json:"feed_id"`
+	UnreadCount int64     `json:"unread_count"`
+}
+
+func databaseUnreadCountsToUnreadCounts(dbCounts []database.GetUnreadCountsForUserRow) []UnreadCount {
+	counts := []UnreadCount{}
+	for _, dbCount := range dbCounts {
+		counts = append(counts, UnreadCount{
+			FeedID:      dbCount.FeedID,
+			UnreadCount: dbCount.UnreadCount,
+		})
+	}
+	return counts
+}
+
+type Folder struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Position  int32     `json:"position"`
+	// Only known when listing folders, left out otherwise
+	UnreadCount *int64 `json:"unread_count,omitempty"`
+}
+
+func databaseFolderToFolder(dbFolder database.Folder) Folder {
+	return Folder{
+		ID:        dbFolder.ID,
+		CreatedAt: dbFolder.CreatedAt,
+		UpdatedAt: dbFolder.UpdatedAt,
+		Name:      dbFolder.Name,
+		Position:  dbFolder.Position,
+	}
+}
+
+func databaseFoldersToFolders(dbFolders []database.GetFoldersForUserRow) []Folder {
+	folders := []Folder{}
+	for _, dbFolder := range dbFolders {
+		folder := databaseFolderToFolder(dbFolder.Folder)
+		folder.UnreadCount = &dbFolder.UnreadCount
+		folders = append(folders, folder)
+	}
+	return folders
+}
+
+type PostRevision struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	PostID      uuid.UUID `json:"post_id"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description"`
+	PublishedAt time.Time `json:"published_at"`
+	Url         string    `json:"url"`
+}
+
+func databasePostRevisionToPostRevision(dbRevision database.PostRevision) PostRevision {
+	var description *string
+	if dbRevision.Description.Valid {
+		description = &dbRevision.Description.String
+	}
+
+	return PostRevision{
+		ID:          dbRevision.ID,
+		CreatedAt:   dbRevision.CreatedAt,
+		PostID:      dbRevision.PostID,
+		Title:       dbRevision.Title,
+		Description: description,
+		PublishedAt: dbRevision.PublishedAt,
+		Url:         dbRevision.Url,
+	}
+}
+
+func databasePostRevisionsToPostRevisions(dbRevisions []database.PostRevision) []PostRevision {
+	revisions := []PostRevision{}
+	for _, dbRevision := range dbRevisions {
+		revisions = append(revisions, databasePostRevisionToPostRevision(dbRevision))
+	}
+	return revisions
+}
+
+// Same idea as description on Post, pointers marshal to null instead of nested struct
+func nullStringToStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullTimeToTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
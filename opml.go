@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// OPML is how feed readers import and export subscriptions
+// Feeds are <outline> elements with an xmlUrl, folders are outlines containing other outlines
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    OPMLHead `xml:"head"`
+	Body    OPMLBody `xml:"body"`
+}
+
+type OPMLHead struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type OPMLBody struct {
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+// One feed found in an OPML file
+// Category is the folder it was in, blank if it was at the top level
+type opmlEntry struct {
+	Title    string
+	URL      string
+	Category string
+}
+
+// Flatten outlines into the feeds they contain
+// Nested folders are joined with "/", e.g. "Tech/Go"
+func (opml OPML) entries() []opmlEntry {
+	entries := []opmlEntry{}
+	var walk func(outlines []OPMLOutline, category string)
+	walk = func(outlines []OPMLOutline, category string) {
+		for _, outline := range outlines {
+			title := firstNonEmpty(outline.Title, outline.Text)
+			if url := strings.TrimSpace(outline.XMLURL); url != "" {
+				entries = append(entries, opmlEntry{
+					Title:    title,
+					URL:      url,
+					Category: category,
+				})
+				continue
+			}
+			// No xmlUrl, a folder
+			folder := category
+			if title != "" {
+				folder = strings.TrimPrefix(category+"/"+title, "/")
+			}
+			walk(outline.Outlines, folder)
+		}
+	}
+	walk(opml.Body.Outlines, "")
+	return entries
+}
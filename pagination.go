@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Most items a client can ask for in one page
+const maxPageSize = 100
+
+// Position in a list, the last item on the previous page
+// At or Rank is whatever the list is sorted by, ID breaks ties
+// Sent to clients base64 encoded so they treat it as opaque and we can change it later
+type pageCursor struct {
+	At   time.Time `json:"p,omitzero"`
+	Rank float32   `json:"r,omitempty"`
+	ID   uuid.UUID `json:"i"`
+}
+
+func (cursor pageCursor) encode() string {
+	dat, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeCursor(value string) (pageCursor, error) {
+	dat, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	cursor := pageCursor{}
+	err = json.Unmarshal(dat, &cursor)
+	if err != nil || cursor.ID == uuid.Nil {
+		return pageCursor{}, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// ?limit= if given, defaultSize otherwise
+// Anything above maxPageSize is capped rather than rejected
+func pageSizeParam(r *http.Request, defaultSize int32) (int32, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultSize, nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 1 {
+		return 0, errors.New("limit must be a positive number")
+	}
+	return int32(min(size, maxPageSize)), nil
+}
+
+// ?cursor= if given
+func cursorParam(r *http.Request) (*pageCursor, error) {
+	value := r.URL.Query().Get("cursor")
+	if value == "" {
+		return nil, nil
+	}
+	cursor, err := decodeCursor(value)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// RFC 3339 time query parameter like ?since=2024-01-02T15:04:05Z, nil if not given
+// Database stores UTC without a zone, so convert
+func timeParam(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 time like 2024-01-02T15:04:05Z", name)
+	}
+	parsed = parsed.UTC()
+	return &parsed, nil
+}
+
+// UUID query parameter like ?feed_id=..., not valid if not given
+func uuidParam(r *http.Request, name string) (uuid.NullUUID, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return uuid.NullUUID{}, nil
+	}
+	parsed, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.NullUUID{}, fmt.Errorf("Couldn't parse %s: %v", name, err)
+	}
+	return uuid.NullUUID{UUID: parsed, Valid: true}, nil
+}
+
+// Tell client where next page is, same URL with cursor swapped in
+// Link header so response body stays a plain list
+func setNextPageLink(w http.ResponseWriter, r *http.Request, cursor pageCursor) {
+	next := *r.URL
+	query := next.Query()
+	query.Set("cursor", cursor.encode())
+	next.RawQuery = query.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+}
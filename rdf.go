@@ -0,0 +1,54 @@
+package main
+
+import "strings"
+
+// RSS 1.0 documents, still common for academic and older indie sources
+// Root element is <rdf:RDF> and items are siblings of <channel> rather than nested inside it
+// encoding/xml matches on local name so don't need the rdf: prefix in tags
+type RDFFeed struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+		// Points at an <image> element by its rdf:resource
+		Image struct {
+			Resource string `xml:"resource,attr"`
+		} `xml:"image"`
+	} `xml:"channel"`
+	Items []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	// rdf:about attribute is the item's URI
+	About       string `xml:"about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	// RSS 1.0 has no pubDate, Dublin Core module is used for dates
+	Date string `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+// Map into RSSFeed so scrapeFeed only deals with one shape
+func (rdfFeed RDFFeed) toRSSFeed() RSSFeed {
+	rssFeed := RSSFeed{
+		Channel: RSSChannel{
+			Title:       strings.TrimSpace(rdfFeed.Channel.Title),
+			Link:        strings.TrimSpace(rdfFeed.Channel.Link),
+			Description: strings.TrimSpace(rdfFeed.Channel.Description),
+			Language:    strings.TrimSpace(rdfFeed.Channel.Language),
+			Image:       RSSImage{URL: strings.TrimSpace(rdfFeed.Channel.Image.Resource)},
+		},
+	}
+
+	for _, item := range rdfFeed.Items {
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			Title:       strings.TrimSpace(item.Title),
+			Link:        strings.TrimSpace(item.Link),
+			Description: strings.TrimSpace(item.Description),
+			DCDate:      strings.TrimSpace(item.Date),
+			GUID:        strings.TrimSpace(item.About),
+		})
+	}
+	return rssFeed
+}
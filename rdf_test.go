@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestParseFeedRDF(t *testing.T) {
+	feed := `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlns="http://purl.org/rss/1.0/"
+	xmlns:dc="http://purl.org/dc/elements/1.1/">
+	<channel rdf:about="https://example.org/rss">
+		<title>Journal</title>
+		<link>https://example.org/</link>
+		<description>Latest papers</description>
+		<dc:language>en</dc:language>
+		<image rdf:resource="https://example.org/logo.png"/>
+	</channel>
+	<image rdf:about="https://example.org/logo.png">
+		<url>https://example.org/logo.png</url>
+	</image>
+	<item rdf:about="https://example.org/papers/1">
+		<title> Paper one </title>
+		<link>https://example.org/papers/1</link>
+		<description>Abstract</description>
+		<dc:date>2024-01-02T03:04:05Z</dc:date>
+	</item>
+	<item rdf:about="https://example.org/papers/2">
+		<title>Paper two</title>
+		<link>https://example.org/papers/2</link>
+	</item>
+</rdf:RDF>`
+
+	rssFeed, err := parseFeed("application/rdf+xml", []byte(feed))
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+	channel := rssFeed.Channel
+	if channel.Title != "Journal" || channel.Link != "https://example.org/" || channel.Description != "Latest papers" {
+		t.Errorf("channel = %q, %q, %q", channel.Title, channel.Link, channel.Description)
+	}
+	if channel.Language != "en" || channel.imageURL() != "https://example.org/logo.png" {
+		t.Errorf("channel language, image = %q, %q", channel.Language, channel.imageURL())
+	}
+
+	// Items sit next to <channel>, not inside it
+	if len(channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(channel.Item))
+	}
+	first := channel.Item[0]
+	if first.Title != "Paper one" || first.Link != "https://example.org/papers/1" || first.Description != "Abstract" {
+		t.Errorf("first item = %q, %q, %q", first.Title, first.Link, first.Description)
+	}
+	// RSS 1.0 dates are dc:date, rdf:about is the item's id
+	if first.DCDate != "2024-01-02T03:04:05Z" || first.PubDate != "" {
+		t.Errorf("first item dates = dc:date %q, pubDate %q", first.DCDate, first.PubDate)
+	}
+	if first.GUID != "https://example.org/papers/1" {
+		t.Errorf("first item guid = %q, want https://example.org/papers/1", first.GUID)
+	}
+	if second := channel.Item[1]; second.DCDate != "" || second.GUID != "https://example.org/papers/2" {
+		t.Errorf("second item dc:date, guid = %q, %q", second.DCDate, second.GUID)
+	}
+}
@@ -1,62 +1,320 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
 // Keys for RSS entries in https://www.wagslane.dev/ blog
 type RSSFeed struct {
-	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Language    string    `xml:"language"`
-		Item        []RSSItem `xml:"item"`
-	} `xml:"channel"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+// Named so other feed formats (Atom) can be mapped into the same shape
+// Fields tagged "-" are filled in from the plain RSS elements, other formats set them directly
+type RSSChannel struct {
+	Title string `xml:"title"`
+	Link  string `xml:"-"`
+	// atom:link rel="self" is also a <link>, so has to be told apart by namespace
+	Links       []RSSText `xml:"link"`
+	Description string    `xml:"description"`
+	Language    string    `xml:"language"`
+	Item        []RSSItem `xml:"item"`
+	// Logo or icon for the feed, podcasts often only have the iTunes one
+	Image RSSImage `xml:"-"`
+	// RSS <image> and <itunes:image> both end up in here
+	Images []RSSImage `xml:"image"`
+	// Software that made the feed, e.g. "Hugo"
+	Generator string `xml:"generator"`
+	// Scheduling hints, ttl is minutes feed can be cached
+	// skipHours and skipDays are when publisher asks us not to fetch
+	TTL       string   `xml:"ttl"`
+	SkipHours []string `xml:"skipHours>hour"`
+	SkipDays  []string `xml:"skipDays>day"`
+}
+
+// Element whose local name other namespaces use too, e.g. <atom:link> or <media:title>
+// encoding/xml matches tags without a namespace to elements in any namespace
+type RSSText struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// Text of the plain RSS element, blank if there's only ones from other namespaces
+func plainRSSText(elements []RSSText) string {
+	for _, element := range elements {
+		if element.XMLName.Space == "" {
+			return element.Value
+		}
+	}
+	return ""
+}
+
+// RSS puts the URL in a child element, iTunes in an href attribute
+type RSSImage struct {
+	XMLName xml.Name
+	URL     string `xml:"url"`
+	Href    string `xml:"href,attr"`
+}
+
+// Whichever image the feed has
+func (channel RSSChannel) imageURL() string {
+	return firstNonEmpty(channel.Image.URL, channel.Image.Href)
+}
+
+// RSS <image> if there is one, otherwise iTunes one
+func plainRSSImage(images []RSSImage) RSSImage {
+	for _, image := range images {
+		if image.XMLName.Space == "" && image.URL != "" {
+			return image
+		}
+	}
+	for _, image := range images {
+		if image.Href != "" {
+			return image
+		}
+	}
+	return RSSImage{}
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string `xml:"-"`
+	Link        string `xml:"-"`
+	Description string `xml:"-"`
+	// media:title, media:description and atom:link end up in these too
+	Titles       []RSSText `xml:"title"`
+	Links        []RSSText `xml:"link"`
+	Descriptions []RSSText `xml:"description"`
+	PubDate      string    `xml:"pubDate"`
+	// Dublin Core date, used by RSS 1.0 and some RSS 2.0 feeds instead of pubDate
+	DCDate string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	// Last modified date, Atom <updated> or atom:updated inside RSS
+	Updated string `xml:"http://www.w3.org/2005/Atom updated"`
+	// Unique identifier publisher gives the item, Atom <id> ends up here too
+	GUID string `xml:"guid"`
+}
+
+// Fill in fields that share a local name with other namespaces from the plain RSS elements
+func (channel *RSSChannel) usePlainRSSElements() {
+	channel.Link = plainRSSText(channel.Links)
+	channel.Image = plainRSSImage(channel.Images)
+	for i := range channel.Item {
+		item := &channel.Item[i]
+		item.Title = plainRSSText(item.Titles)
+		item.Link = plainRSSText(item.Links)
+		item.Description = plainRSSText(item.Descriptions)
+	}
+}
+
+// What came back from fetching a feed
+// NotModified means server answered 304 and RSSFeed is empty
+// CacheMaxAge and RetryAfter are 0 when server didn't send them
+// MovedTo is where feed permanently moved, blank if it didn't
+// Page and PageURL are only set with errNotAFeed, what came back and where from after redirects
+// so a web page can be looked through for feeds without fetching it again
+type FeedFetch struct {
+	RSSFeed      RSSFeed
+	NotModified  bool
+	ETag         string
+	LastModified string
+	CacheMaxAge  time.Duration
+	RetryAfter   time.Duration
+	MovedTo      string
+	Page         []byte
+	PageURL      *url.URL
 }
 
+// Biggest feed we'll read, podcasts with years of episodes can run to a few MB
+const maxFeedSize = 10 << 20
+
+// URL answered but what came back isn't RSS, Atom or JSON Feed, e.g. a web page
+var errNotAFeed = errors.New("not an RSS, Atom or JSON feed")
+
 // Parse
-func urlToFeed(url string) (RSSFeed, error) {
+// etag and lastModified are validators from previous fetch, blank if don't have any
+// timeout covers the whole fetch, redirects and reading the body included
+func urlToFeed(ctx context.Context, url, etag, lastModified string, timeout time.Duration) (FeedFetch, error) {
 	// Need HTTP Client
 	// Create using http library
+	// Only count redirects as a move while every hop is permanent (301/308)
+	// A temporary hop anywhere means original URL is still the one to use
+	movedTo := ""
+	permanent := true
 	httpClient := http.Client{
-		// Set Timeout to 10s, more than 10s to fetch, don't want, probably broken
-		Timeout: 10 * time.Second,
+		// Longer than timeout to fetch, don't want, probably broken
+		Timeout: timeout,
+		// Won't connect to internal addresses, redirect hops included
+		Transport: feedTransport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Same limit default client uses
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			status := req.Response.StatusCode
+			if status != http.StatusMovedPermanently && status != http.StatusPermanentRedirect {
+				permanent = false
+			}
+			if permanent {
+				movedTo = req.URL.String()
+			}
+			return nil
+		},
+	}
+
+	// Build request ourselves rather than httpClient.Get so can set headers
+	// Request is aborted if ctx is cancelled
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return FeedFetch{}, err
+	}
+	// Conditional GET, server can skip sending body if nothing changed
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
 	}
 
 	// Use Client to make GET request to URL of feed
 	// Return http response
-	resp, err := httpClient.Get(url)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		// Return empty structs
-		return RSSFeed{}, err
+		return FeedFetch{}, err
 	}
 	defer resp.Body.Close()
 
+	// Nothing changed since last fetch, keep the validators we already have
+	if resp.StatusCode == http.StatusNotModified {
+		return FeedFetch{
+			NotModified:  true,
+			ETag:         etag,
+			LastModified: lastModified,
+			CacheMaxAge:  cacheMaxAge(resp.Header),
+			MovedTo:      movedTo,
+		}, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// 429 and 503 usually say when we can come back
+		return FeedFetch{
+			RetryAfter: retryAfter(resp.Header, time.Now()),
+		}, fmt.Errorf("unexpected status fetching feed: %s", resp.Status)
+	}
+
 	// Get all data from response body
-	dat, err := io.ReadAll(resp.Body)
+	// One more byte than allowed so can tell a feed that's exactly the limit from one that's over
+	dat, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
 	if err != nil {
-		return RSSFeed{}, err
+		return FeedFetch{}, err
+	}
+	if len(dat) > maxFeedSize {
+		return FeedFetch{}, fmt.Errorf("feed is bigger than %d bytes", maxFeedSize)
+	}
+
+	rssFeed, err := parseFeed(resp.Header.Get("Content-Type"), dat)
+	if err != nil && !errors.Is(err, errNotAFeed) {
+		err = fmt.Errorf("%w: %v", errNotAFeed, err)
+	}
+	if err != nil {
+		return FeedFetch{Page: dat, PageURL: resp.Request.URL}, err
+	}
+	return FeedFetch{
+		RSSFeed:      rssFeed,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		CacheMaxAge:  cacheMaxAge(resp.Header),
+		MovedTo:      movedTo,
+	}, nil
+}
+
+// Look at content type and root element to know which format we're dealing with
+// JSON Feed is JSON, everything else is XML
+// RSS 2.0 documents start with <rss>, RSS 1.0 with <rdf:RDF>, Atom with <feed>
+func parseFeed(contentType string, dat []byte) (RSSFeed, error) {
+	// Servers often send feeds as text/plain or application/octet-stream
+	// so also sniff the body rather than only trusting the header
+	if isJSONContentType(contentType) || bytes.HasPrefix(bytes.TrimSpace(dat), []byte("{")) {
+		jsonFeed := JSONFeed{}
+		err := json.Unmarshal(dat, &jsonFeed)
+		if err != nil {
+			return RSSFeed{}, err
+		}
+		// Any JSON object unmarshals fine, version is what says it's a feed
+		if !strings.HasPrefix(jsonFeed.Version, "https://jsonfeed.org/version/") {
+			return RSSFeed{}, errNotAFeed
+		}
+		return jsonFeed.toRSSFeed(), nil
 	}
-	rssFeed := RSSFeed{}
 
-	// Want to read into RSSFeed
-	// Similar to dealing with JSON
-	// Pointer to where we want to unmarshal the data into that location in memory
-	err = xml.Unmarshal(dat, &rssFeed)
+	root, err := xmlRootElement(dat)
 	if err != nil {
 		return RSSFeed{}, err
 	}
-	// Can just return populated RSSFeed
-	return rssFeed, nil
+
+	switch root.Local {
+	case "rss":
+		rssFeed := RSSFeed{}
+		// Want to read into RSSFeed
+		// Similar to dealing with JSON
+		// Pointer to where we want to unmarshal the data into that location in memory
+		err = xml.Unmarshal(dat, &rssFeed)
+		if err != nil {
+			return RSSFeed{}, err
+		}
+		rssFeed.Channel.usePlainRSSElements()
+		// Can just return populated RSSFeed
+		return rssFeed, nil
+	case "feed":
+		atomFeed := AtomFeed{}
+		err = xml.Unmarshal(dat, &atomFeed)
+		if err != nil {
+			return RSSFeed{}, err
+		}
+		// Map Atom into RSSFeed so scraper only deals with one shape
+		return atomFeed.toRSSFeed(), nil
+	case "RDF":
+		rdfFeed := RDFFeed{}
+		err = xml.Unmarshal(dat, &rdfFeed)
+		if err != nil {
+			return RSSFeed{}, err
+		}
+		return rdfFeed.toRSSFeed(), nil
+	}
+	return RSSFeed{}, fmt.Errorf("unsupported feed format: <%s>", root.Local)
+}
+
+// application/feed+json is registered type, some servers just send application/json
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/feed+json" || mediaType == "application/json"
+}
+
+// Skip over XML declaration, comments and whitespace until first element
+func xmlRootElement(dat []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(dat))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return xml.Name{}, errors.New("no root element found in feed")
+			}
+			return xml.Name{}, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
 }
@@ -0,0 +1,111 @@
+package main
+
+import "testing"
+
+func TestParseFeedRSSNamespacedElements(t *testing.T) {
+	tests := []struct {
+		name            string
+		feed            string
+		wantLink        string
+		wantImage       string
+		wantTitle       string
+		wantItemLink    string
+		wantDescription string
+	}{
+		{
+			name: "plain RSS",
+			feed: `<rss version="2.0"><channel>
+				<title>Blog</title><link>https://example.com/</link>
+				<image><url>https://example.com/logo.png</url></image>
+				<item><title>Post</title><link>https://example.com/post</link><description>Body</description></item>
+			</channel></rss>`,
+			wantLink:        "https://example.com/",
+			wantImage:       "https://example.com/logo.png",
+			wantTitle:       "Post",
+			wantItemLink:    "https://example.com/post",
+			wantDescription: "Body",
+		},
+		{
+			name: "atom:link after channel and item links",
+			feed: `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+				<title>Blog</title><link>https://example.com/</link>
+				<atom:link href="https://example.com/index.xml" rel="self" type="application/rss+xml"/>
+				<item><title>Post</title><link>https://example.com/post</link>
+					<atom:link href="https://example.com/post/comments" rel="replies"/>
+					<description>Body</description></item>
+			</channel></rss>`,
+			wantLink:        "https://example.com/",
+			wantTitle:       "Post",
+			wantItemLink:    "https://example.com/post",
+			wantDescription: "Body",
+		},
+		{
+			name: "media:title and media:description",
+			feed: `<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/"><channel>
+				<title>Videos</title><link>https://example.com/</link>
+				<item><title>Episode 1</title><link>https://example.com/1</link><description>Notes</description>
+					<media:content url="https://example.com/1.mp4">
+						<media:title>episode-1.mp4</media:title>
+					</media:content>
+					<media:title>Media title</media:title>
+					<media:description>Media description</media:description>
+				</item>
+			</channel></rss>`,
+			wantLink:        "https://example.com/",
+			wantTitle:       "Episode 1",
+			wantItemLink:    "https://example.com/1",
+			wantDescription: "Notes",
+		},
+		{
+			name: "only itunes:image",
+			feed: `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel>
+				<title>Podcast</title><link>https://example.com/</link>
+				<itunes:image href="https://example.com/cover.jpg"/>
+			</channel></rss>`,
+			wantLink:  "https://example.com/",
+			wantImage: "https://example.com/cover.jpg",
+		},
+		{
+			name: "itunes:image after RSS image",
+			feed: `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel>
+				<title>Podcast</title><link>https://example.com/</link>
+				<image><url>https://example.com/logo.png</url></image>
+				<itunes:image href="https://example.com/cover.jpg"/>
+			</channel></rss>`,
+			wantLink:  "https://example.com/",
+			wantImage: "https://example.com/logo.png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rssFeed, err := parseFeed("application/rss+xml", []byte(tt.feed))
+			if err != nil {
+				t.Fatalf("parseFeed returned error: %v", err)
+			}
+			channel := rssFeed.Channel
+			if channel.Link != tt.wantLink {
+				t.Errorf("channel link = %q, want %q", channel.Link, tt.wantLink)
+			}
+			if channel.imageURL() != tt.wantImage {
+				t.Errorf("image = %q, want %q", channel.imageURL(), tt.wantImage)
+			}
+			if tt.wantTitle == "" {
+				return
+			}
+			if len(channel.Item) != 1 {
+				t.Fatalf("got %d items, want 1", len(channel.Item))
+			}
+			item := channel.Item[0]
+			if item.Title != tt.wantTitle {
+				t.Errorf("item title = %q, want %q", item.Title, tt.wantTitle)
+			}
+			if item.Link != tt.wantItemLink {
+				t.Errorf("item link = %q, want %q", item.Link, tt.wantItemLink)
+			}
+			if item.Description != tt.wantDescription {
+				t.Errorf("item description = %q, want %q", item.Description, tt.wantDescription)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+)
+
+// Bounds on how often a feed gets fetched
+// News wires still wait minFetchInterval, feeds that post twice a year still get checked daily
+const (
+	minFetchInterval     = 5 * time.Minute
+	maxFetchInterval     = 24 * time.Hour
+	defaultFetchInterval = time.Hour
+)
+
+// How many recent posts to look at when working out posting frequency
+const postingFrequencySample = 10
+
+// Hints from the response that say when we're allowed to fetch again
+// Channel fields are only in a full body, they're stored on the feed for when we get a 304
+type fetchHints struct {
+	TTL         time.Duration
+	SkipHours   map[int]bool
+	SkipDays    map[time.Weekday]bool
+	CacheMaxAge time.Duration
+}
+
+// Work out when a feed should next be fetched
+// Starts from posting frequency and then makes sure publisher's hints are respected
+func nextFetchAt(now time.Time, postDates []time.Time, hints fetchHints) time.Time {
+	interval := postingInterval(postDates)
+
+	// Never fetch sooner than publisher asked for
+	for _, atLeast := range []time.Duration{hints.TTL, hints.CacheMaxAge} {
+		if atLeast > interval {
+			interval = atLeast
+		}
+	}
+	if interval < minFetchInterval {
+		interval = minFetchInterval
+	}
+	if interval > maxFetchInterval {
+		interval = maxFetchInterval
+	}
+
+	return skipUntilAllowed(now.Add(interval).UTC(), hints.SkipHours, hints.SkipDays)
+}
+
+// Wait twice as long after every failure in a row, up to maxFetchInterval
+// Retry-After wins if server asked for longer
+func failureBackoff(failures int32, retryAfter time.Duration) time.Duration {
+	backoff := minFetchInterval
+	for i := int32(1); i < failures && backoff < maxFetchInterval; i++ {
+		backoff *= 2
+	}
+	if backoff > maxFetchInterval {
+		backoff = maxFetchInterval
+	}
+	if retryAfter > backoff {
+		backoff = retryAfter
+	}
+	return backoff
+}
+
+// Fetch twice as often as feed publishes on average, so new posts show up within half a cycle
+func postingInterval(postDates []time.Time) time.Duration {
+	if len(postDates) < 2 {
+		return defaultFetchInterval
+	}
+
+	sorted := append([]time.Time{}, postDates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	averageGap := sorted[len(sorted)-1].Sub(sorted[0]) / time.Duration(len(sorted)-1)
+	if averageGap <= 0 {
+		return defaultFetchInterval
+	}
+	return averageGap / 2
+}
+
+// skipHours and skipDays are in GMT
+// Move forward an hour at a time until out of skipped period, at most a week
+func skipUntilAllowed(at time.Time, skipHours map[int]bool, skipDays map[time.Weekday]bool) time.Time {
+	for i := 0; i < 24*7; i++ {
+		if !skipHours[at.Hour()] && !skipDays[at.Weekday()] {
+			return at
+		}
+		at = at.Add(time.Hour).Truncate(time.Hour)
+	}
+	return at
+}
+
+// Read <ttl>, <skipHours> and <skipDays> from RSS channel
+// Invalid values are ignored rather than failing the fetch
+func channelFetchHints(channel RSSChannel) fetchHints {
+	hints := fetchHints{
+		SkipHours: map[int]bool{},
+		SkipDays:  map[time.Weekday]bool{},
+	}
+
+	// ttl is number of minutes feed can be cached
+	if ttl, err := strconv.Atoi(strings.TrimSpace(channel.TTL)); err == nil && ttl > 0 {
+		hints.TTL = time.Duration(ttl) * time.Minute
+	}
+
+	for _, hour := range channel.SkipHours {
+		h, err := strconv.Atoi(strings.TrimSpace(hour))
+		if err == nil && h >= 0 && h <= 23 {
+			hints.SkipHours[h] = true
+		}
+	}
+	// Skipping every hour or every day would mean never fetching, ignore
+	if len(hints.SkipHours) == 24 {
+		hints.SkipHours = map[int]bool{}
+	}
+
+	for _, day := range channel.SkipDays {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+				hints.SkipDays[weekday] = true
+			}
+		}
+	}
+	if len(hints.SkipDays) == 7 {
+		hints.SkipDays = map[time.Weekday]bool{}
+	}
+	return hints
+}
+
+// Channel hints as stored on the feed, days are time.Weekday numbers
+func (hints fetchHints) toFeedParams(feedID uuid.UUID) database.UpdateFeedFetchHintsParams {
+	params := database.UpdateFeedFetchHintsParams{
+		ID:         feedID,
+		TtlMinutes: int32(hints.TTL / time.Minute),
+		SkipHours:  []int32{},
+		SkipDays:   []int32{},
+	}
+	for hour := range hints.SkipHours {
+		params.SkipHours = append(params.SkipHours, int32(hour))
+	}
+	for day := range hints.SkipDays {
+		params.SkipDays = append(params.SkipDays, int32(day))
+	}
+	slices.Sort(params.SkipHours)
+	slices.Sort(params.SkipDays)
+	return params
+}
+
+// Channel hints from the last full fetch, for when the feed answers 304
+func storedFetchHints(feed database.Feed) fetchHints {
+	hints := fetchHints{
+		TTL:       time.Duration(feed.TtlMinutes) * time.Minute,
+		SkipHours: map[int]bool{},
+		SkipDays:  map[time.Weekday]bool{},
+	}
+	for _, hour := range feed.SkipHours {
+		hints.SkipHours[int(hour)] = true
+	}
+	for _, day := range feed.SkipDays {
+		hints.SkipDays[time.Weekday(day)] = true
+	}
+	return hints
+}
+
+// max-age from Cache-Control, 0 if there isn't one
+func cacheMaxAge(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(directive), "=")
+		if !found || !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
+
+// Retry-After is either a number of seconds or an HTTP date
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jakeleesh/rssagg/internal/database"
+)
+
+func TestNextFetchAtClampsInterval(t *testing.T) {
+	// Monday, no skipped hours or days
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		postDates []time.Time
+		hints     fetchHints
+		want      time.Duration
+	}{
+		{
+			name: "not enough posts for a frequency",
+			want: defaultFetchInterval,
+		},
+		{
+			name:      "posting every 4 hours fetches every 2",
+			postDates: []time.Time{now.Add(-8 * time.Hour), now.Add(-4 * time.Hour), now},
+			want:      2 * time.Hour,
+		},
+		{
+			name:      "busy feed is not fetched more often than the minimum",
+			postDates: []time.Time{now.Add(-2 * time.Minute), now.Add(-time.Minute), now},
+			want:      minFetchInterval,
+		},
+		{
+			name:      "quiet feed is still fetched daily",
+			postDates: []time.Time{now.AddDate(0, -6, 0), now},
+			want:      maxFetchInterval,
+		},
+		{
+			name:      "ttl longer than posting frequency wins",
+			postDates: []time.Time{now.Add(-time.Hour), now},
+			hints:     fetchHints{TTL: 3 * time.Hour},
+			want:      3 * time.Hour,
+		},
+		{
+			name:  "Cache-Control max-age longer than ttl wins",
+			hints: fetchHints{TTL: 2 * time.Hour, CacheMaxAge: 4 * time.Hour},
+			want:  4 * time.Hour,
+		},
+		{
+			name:  "ttl is capped at the maximum",
+			hints: fetchHints{TTL: 7 * 24 * time.Hour},
+			want:  maxFetchInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextFetchAt(now, tt.postDates, tt.hints)
+			if want := now.Add(tt.want); !got.Equal(want) {
+				t.Errorf("nextFetchAt() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNextFetchAtSkips(t *testing.T) {
+	// Friday 22:00 GMT, default interval lands on 23:00
+	now := time.Date(2024, 1, 5, 22, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		hints fetchHints
+		want  time.Time
+	}{
+		{
+			name: "nothing skipped",
+			want: time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "skipped hour moves to the next one",
+			hints: fetchHints{SkipHours: map[int]bool{23: true}},
+			want:  time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "weekend skipped moves to Monday",
+			hints: fetchHints{
+				SkipHours: map[int]bool{23: true},
+				SkipDays:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+			},
+			want: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "skipped hours on the allowed day too",
+			hints: fetchHints{
+				SkipHours: map[int]bool{23: true, 0: true, 1: true},
+				SkipDays:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+			},
+			want: time.Date(2024, 1, 8, 2, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextFetchAt(now, nil, tt.hints); !got.Equal(tt.want) {
+				t.Errorf("nextFetchAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannelFetchHints(t *testing.T) {
+	hints := channelFetchHints(RSSChannel{
+		TTL:       " 90 ",
+		SkipHours: []string{"0", "23", "24", "-1", "noon"},
+		SkipDays:  []string{"saturday", " Sunday ", "Someday"},
+	})
+	if hints.TTL != 90*time.Minute {
+		t.Errorf("TTL = %v, want 1h30m", hints.TTL)
+	}
+	if len(hints.SkipHours) != 2 || !hints.SkipHours[0] || !hints.SkipHours[23] {
+		t.Errorf("SkipHours = %v, want 0 and 23", hints.SkipHours)
+	}
+	if len(hints.SkipDays) != 2 || !hints.SkipDays[time.Saturday] || !hints.SkipDays[time.Sunday] {
+		t.Errorf("SkipDays = %v, want Saturday and Sunday", hints.SkipDays)
+	}
+
+	// Skipping everything would mean never fetching
+	allHours := []string{}
+	for h := 0; h < 24; h++ {
+		allHours = append(allHours, strconv.Itoa(h))
+	}
+	allDays := []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+	hints = channelFetchHints(RSSChannel{TTL: "0", SkipHours: allHours, SkipDays: allDays})
+	if hints.TTL != 0 || len(hints.SkipHours) != 0 || len(hints.SkipDays) != 0 {
+		t.Errorf("channelFetchHints() = %+v, want no hints", hints)
+	}
+}
+
+func TestStoredFetchHints(t *testing.T) {
+	hints := channelFetchHints(RSSChannel{
+		TTL:       "120",
+		SkipHours: []string{"23", "0"},
+		SkipDays:  []string{"Sunday", "Saturday"},
+	})
+	params := hints.toFeedParams(uuid.New())
+	if params.TtlMinutes != 120 || !slices.Equal(params.SkipHours, []int32{0, 23}) || !slices.Equal(params.SkipDays, []int32{0, 6}) {
+		t.Errorf("toFeedParams() = %+v", params)
+	}
+
+	// What a 304 schedules from
+	stored := storedFetchHints(database.Feed{
+		TtlMinutes: params.TtlMinutes,
+		SkipHours:  params.SkipHours,
+		SkipDays:   params.SkipDays,
+	})
+	if stored.TTL != hints.TTL || !maps.Equal(stored.SkipHours, hints.SkipHours) || !maps.Equal(stored.SkipDays, hints.SkipDays) {
+		t.Errorf("storedFetchHints() = %+v, want %+v", stored, hints)
+	}
+}
+
+func TestFailureBackoff(t *testing.T) {
+	tests := []struct {
+		failures   int32
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{failures: 1, want: minFetchInterval},
+		{failures: 2, want: 2 * minFetchInterval},
+		{failures: 4, want: 8 * minFetchInterval},
+		{failures: 50, want: maxFetchInterval},
+		{failures: 1, retryAfter: time.Hour, want: time.Hour},
+		{failures: 50, retryAfter: 48 * time.Hour, want: 48 * time.Hour},
+		{failures: 4, retryAfter: time.Minute, want: 8 * minFetchInterval},
+	}
+
+	for _, tt := range tests {
+		if got := failureBackoff(tt.failures, tt.retryAfter); got != tt.want {
+			t.Errorf("failureBackoff(%d, %v) = %v, want %v", tt.failures, tt.retryAfter, got, tt.want)
+		}
+	}
+}
+
+func TestCacheMaxAgeAndRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	header := http.Header{}
+	header.Set("Cache-Control", "public, max-age=600")
+	header.Set("Retry-After", "Mon, 01 Jan 2024 12:30:00 GMT")
+	if got := cacheMaxAge(header); got != 10*time.Minute {
+		t.Errorf("cacheMaxAge() = %v, want 10m", got)
+	}
+	if got := retryAfter(header, now); got != 30*time.Minute {
+		t.Errorf("retryAfter() = %v, want 30m", got)
+	}
+
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Retry-After", "120")
+	if got := cacheMaxAge(header); got != 0 {
+		t.Errorf("cacheMaxAge() = %v, want 0", got)
+	}
+	if got := retryAfter(header, now); got != 2*time.Minute {
+		t.Errorf("retryAfter() = %v, want 2m", got)
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"log"
 	"strings"
 	"sync"
@@ -12,125 +15,533 @@ import (
 	"github.com/jakeleesh/rssagg/internal/database"
 )
 
+// How long an instance holds a feed beyond the fetch timeout, time to store what came back
+// Lease only runs out if instance crashed mid-scrape, however long fetch_timeout is set
+const feedLeaseMargin = 5 * time.Minute
+
+// Same idea as apiConfig, holds what scraping needs so functions don't take a long list of parameters
+type scraper struct {
+	// Exposed by code generated using sqlc
+	DB *database.Queries
+	// Raw connection, only needed to start transactions
+	conn *sql.DB
+	// How many times in a row a feed can fail before we stop fetching it
+	maxFailures int
+	// How long a single fetch can take
+	fetchTimeout time.Duration
+}
+
+// How long an instance can hold a feed before others may claim it
+func (scr *scraper) leaseDuration() time.Duration {
+	return scr.fetchTimeout + feedLeaseMargin
+}
+
 // Scrapper is a long running job, running background as Server runs
 // Inputs:
-// A connection to database
+// Context that gets cancelled when server shuts down
 // Concurrency units: How many goroutines want to do the scraping
 // How much time we want in between each request to go scrape a new RSSFeed
 // Shouldn't return anything because going to be a long running job
-func startScraping(db *database.Queries, concurrency int, timeBetweenRequest time.Duration) {
+// Returns once ctx is cancelled and feeds being scraped are done
+func (scr *scraper) startScraping(ctx context.Context, concurrency int, timeBetweenRequest time.Duration) {
 	// Scraper running in background of server, important have good logging, tells us what's going on
 	log.Printf("Scraping on %v goroutines every %s duration", concurrency, timeBetweenRequest)
 	// Make request on interval
 	// Responds with a ticker
 	ticker := time.NewTicker(timeBetweenRequest)
+	defer ticker.Stop()
 
-	// Use for loop to execute every time new value comes across ticker's channel
+	// Scrape straight away, then every time new value comes across ticker's channel
 	// ticker has field C, which is a channel where value sent across the channel
-	// Passing in empty initializes and middle is so that it executes immediately
-	// If did for range ticker.C, will wait
-	for ; ; <-ticker.C {
-		// context.Background() is global context
-		// Use if don't have access to scoped context like for individual http requests
-		feeds, err := db.GetNextFeedsToFetch(context.Background(), int32(concurrency))
-		if err != nil {
-			log.Printf("error fetching feeds:", err)
-			// Continue because function should always be running as server operates
-			continue
-		}
+	for {
+		scr.scrapeNextFeeds(ctx, concurrency)
 
-		// Fetches each feed individually at the same time
-		// Need synchronization mechanism: Use WaitGroup
-		wg := &sync.WaitGroup{}
-		// Iterating over all feeds we want to fetch on individual goroutines
-		for _, feed := range feeds {
-			// The way WaitGroup works:
-			// Anytime want spawn new goroutine within context of WaitGroup, add number to it
-			// Iterating over all feeds on the same goroutine startScraping function
-			// Adding 1 to WaitGroup for every feed
-			// Had concurrency of 30, adding 30 to WaitGroup
-			// Spawning seperate goroutines
-			// End of loop, waiting on WaitGroup for 30 distinct calls to wg.Done()
-			// wg.Done() decrements counter by 1, adding 1 everytime iterate over slice
-			// Calling done when done scraping feed
-			// ALlows us to scrape feed same time 30 times
-			// Spawn 30 different gorountines to scrape 30 different RSSFeed
-			wg.Add(1)
-
-			// Spawn new goroutine, pass WaitGroup in
-			go scrapeFeed(db, wg, feed)
+		// Wait for next tick, or stop if shutting down
+		// Feeds from last round are already done, scrapeNextFeeds waits for them
+		select {
+		case <-ctx.Done():
+			log.Println("Scraper stopped")
+			return
+		case <-ticker.C:
 		}
-		// When all done, will execute
-		// Before done, will be blocking
-		// Don't want to continue next iteration of loop until sure scraped all feeds
-		wg.Wait()
 	}
 }
 
-// Pointer to WaitGroup
-func scrapeFeed(db *database.Queries, wg *sync.WaitGroup, feed database.Feed) {
-	// Decrements counter by 1
-	// Deferring so will always be called at end of function
-	defer wg.Done()
-
-	// Mark that we're fetching this feed
-	// Returns updated feed, can ignore
-	_, err := db.MarkFeedAsFetched(context.Background(), feed.ID)
+// One round of scraping, fetches up to concurrency feeds that are due
+func (scr *scraper) scrapeNextFeeds(ctx context.Context, concurrency int) {
+	// Claiming is atomic, other server instances never get the same feeds
+	now := time.Now().UTC()
+	feeds, err := scr.DB.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{
+		Now:            now,
+		LeaseExpiresAt: sql.NullTime{Time: now.Add(scr.leaseDuration()), Valid: true},
+		Limit:          int32(concurrency),
+	})
 	if err != nil {
-		// Not returning anything from function, calling on new goroutine so nothign to return
-		// Just log there was an issue
-		log.Println("Error marking feed as fetched:", err)
+		log.Println("error fetching feeds:", err)
+		// Return because function should always be running as server operates, try again next tick
 		return
 	}
 
+	// Fetches each feed individually at the same time
+	// Need synchronization mechanism: Use WaitGroup
+	wg := &sync.WaitGroup{}
+	// Iterating over all feeds we want to fetch on individual goroutines
+	for _, feed := range feeds {
+		// The way WaitGroup works:
+		// Anytime want spawn new goroutine within context of WaitGroup, add number to it
+		// Iterating over all feeds on the same goroutine startScraping function
+		// Adding 1 to WaitGroup for every feed
+		// Had concurrency of 30, adding 30 to WaitGroup
+		// Spawning seperate goroutines
+		// End of loop, waiting on WaitGroup for 30 distinct calls to wg.Done()
+		// wg.Done() decrements counter by 1, adding 1 everytime iterate over slice
+		// Calling done when done scraping feed
+		// ALlows us to scrape feed same time 30 times
+		// Spawn 30 different gorountines to scrape 30 different RSSFeed
+		wg.Add(1)
+
+		// Spawn new goroutine
+		// ctx cancelled on shutdown aborts fetches and queries still running
+		go func() {
+			// Decrements counter by 1
+			// Deferring so will always be called at end of function
+			defer wg.Done()
+			scr.scrapeFeed(ctx, feed)
+		}()
+	}
+	// When all done, will execute
+	// Before done, will be blocking
+	// Don't want to start next round until sure scraped all feeds
+	wg.Wait()
+}
+
+// What happened when scrapeFeed fetched a feed
+type fetchOutcome struct {
+	// fetched, not_modified or failed
+	Status       string `json:"status"`
+	PostsFound   int    `json:"posts_found"`
+	NewPosts     int    `json:"new_posts"`
+	UpdatedPosts int    `json:"updated_posts"`
+	// Set when feed permanently moved and we followed it
+	MovedTo string `json:"moved_to,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+const (
+	fetchStatusFetched     = "fetched"
+	fetchStatusNotModified = "not_modified"
+	fetchStatusFailed      = "failed"
+)
+
+// Fetch a feed we hold the lease for and store its posts
+// Returns when done, callers run it on its own goroutine if they want concurrency
+// Worker loop only logs, outcome is for callers that want to report back, like refreshing from the API
+func (scr *scraper) scrapeFeed(ctx context.Context, feed database.Feed) fetchOutcome {
+	// Feed was claimed with a lease, give it up when done however scrape went
+	// Not cancelled with ctx so leases are still released when shutting down
+	defer scr.releaseLease(context.WithoutCancel(ctx), feed.ID)
+
 	// Scrape Feed
-	rssFeed, err := urlToFeed(feed.Url)
+	// Send back validators from last time so server can answer 304
+	feedFetch, err := urlToFeed(ctx, feed.Url, feed.Etag.String, feed.LastModified.String, scr.fetchTimeout)
 	if err != nil {
 		log.Println("Error fetching feed:", err)
-		return
+		outcome := fetchOutcome{Status: fetchStatusFailed, Error: err.Error()}
+		// Aborted because we're shutting down, not the feed's fault
+		if ctx.Err() != nil {
+			return outcome
+		}
+		scr.recordFetchFailure(ctx, feed, err, feedFetch.RetryAfter)
+		return outcome
 	}
 
-	for _, item := range rssFeed.Channel.Item {
-		// NullString has string itself and whether it's valid
-		// If item description is blank, set the value to null in database
-		// Otherwise create valid description
-		description := sql.NullString{}
-		if item.Description != "" {
-			description.String = item.Description
-			description.Valid = true
+	err = scr.DB.RecordFeedFetchSuccess(ctx, database.RecordFeedFetchSuccessParams{
+		Now: time.Now().UTC(),
+		ID:  feed.ID,
+	})
+	if err != nil {
+		log.Println("Error recording feed fetch success:", err)
+	}
+	outcome := fetchOutcome{Status: fetchStatusFetched}
+
+	// Feed permanently moved, stop paying for the redirect on every fetch
+	// Only trust the move once new URL served us a working feed
+	if feedFetch.MovedTo != "" && feedFetch.MovedTo != feed.Url {
+		moved, err := scr.moveFeed(ctx, feed, feedFetch.MovedTo)
+		if err != nil {
+			log.Printf("Error moving feed %s to %s: %v", feed.Name, feedFetch.MovedTo, err)
+		} else {
+			log.Printf("Feed %s moved from %s to %s", feed.Name, feed.Url, moved.Url)
+			feed = moved
+			outcome.MovedTo = moved.Url
 		}
+	}
+
+	// Nothing changed, successful fetch with no work to do
+	if feedFetch.NotModified {
+		log.Printf("Feed %s not modified", feed.Name)
+		// Channel hasn't changed, so neither have its ttl, skipHours and skipDays
+		hints := storedFetchHints(feed)
+		hints.CacheMaxAge = feedFetch.CacheMaxAge
+		scr.scheduleNextFetch(ctx, feed, hints)
+		outcome.Status = fetchStatusNotModified
+		return outcome
+	}
+
+	err = scr.DB.UpdateFeedCacheHeaders(ctx, database.UpdateFeedCacheHeadersParams{
+		ID:           feed.ID,
+		Etag:         nullString(feedFetch.ETag),
+		LastModified: nullString(feedFetch.LastModified),
+		UpdatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		log.Println("Error updating feed cache headers:", err)
+	}
+
+	rssFeed := feedFetch.RSSFeed
+	// Keep what the feed says about itself current, e.g. a new logo
+	channel := rssFeed.Channel
+	err = scr.DB.UpdateFeedMetadata(ctx, database.UpdateFeedMetadataParams{
+		ID:          feed.ID,
+		Description: nullString(strings.TrimSpace(channel.Description)),
+		SiteUrl:     nullString(strings.TrimSpace(channel.Link)),
+		Language:    nullString(strings.TrimSpace(channel.Language)),
+		ImageUrl:    nullString(channel.imageURL()),
+		Generator:   nullString(strings.TrimSpace(channel.Generator)),
+	})
+	if err != nil {
+		log.Println("Error updating feed metadata:", err)
+	}
+	// Last resort for items without a usable date
+	fetchedAt := time.Now().UTC()
+	outcome.PostsFound = len(rssFeed.Channel.Item)
 
-		// Need to parse
-		// RFC1123Z is layout
-		pubAt, err := time.Parse(time.RFC1123Z, item.PubDate)
+	for _, item := range rssFeed.Channel.Item {
+		saved, err := scr.savePost(ctx, feed, item, fetchedAt)
 		if err != nil {
-			log.Printf("couldn't parse date %v with err %v", item.PubDate, err)
+			log.Println("failed to save post", err)
 			continue
 		}
+		switch saved {
+		case postCreated:
+			outcome.NewPosts++
+		case postUpdated:
+			outcome.UpdatedPosts++
+		}
+	}
+
+	log.Printf("Feed %s collected, %v posts found, %v new, %v updated", feed.Name, outcome.PostsFound, outcome.NewPosts, outcome.UpdatedPosts)
+
+	hints := channelFetchHints(rssFeed.Channel)
+	err = scr.DB.UpdateFeedFetchHints(ctx, hints.toFeedParams(feed.ID))
+	if err != nil {
+		log.Println("Error storing feed fetch hints:", err)
+	}
+	hints.CacheMaxAge = feedFetch.CacheMaxAge
+	scr.scheduleNextFetch(ctx, feed, hints)
+	return outcome
+}
+
+func (scr *scraper) releaseLease(ctx context.Context, feedID uuid.UUID) {
+	err := scr.DB.ReleaseFeedLease(ctx, feedID)
+	if err != nil {
+		// Not fatal, lease runs out by itself
+		log.Println("Error releasing feed lease:", err)
+	}
+}
+
+// Point feed at its new URL
+// If another feed already has that URL, merge into it: move follows and posts over and delete this one
+// Returns the feed that now has the new URL
+func (scr *scraper) moveFeed(ctx context.Context, feed database.Feed, newURL string) (database.Feed, error) {
+	// Either everything moves or nothing does
+	tx, err := scr.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	// No-op once committed
+	defer tx.Rollback()
+	qtx := scr.DB.WithTx(tx)
 
-		// Don't care about new post
-		_, err = db.CreatePost(context.Background(), database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now().UTC(),
-			UpdatedAt:   time.Now().UTC(),
-			Title:       item.Title,
-			Description: description,
-			PublishedAt: pubAt,
-			Url:         item.Link,
-			FeedID:      feed.ID,
+	existing, err := qtx.GetFeedByURL(ctx, newURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		moved, err := qtx.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+			ID:        feed.ID,
+			Url:       newURL,
+			UpdatedAt: time.Now().UTC(),
 		})
 		if err != nil {
-			// Scrapped feeds and pulled in posts
-			// "duplicate key violates unique constraints" makes sense, didn't want to store duplicate posts in database
-			// Try to recreate post, fails because already have posts in database
-			// String detection, don't log this, isn't an error, expected behavior
-			if strings.Contains(err.Error(), "duplicate key") {
-				continue
-			}
-			// Log error if not "duplicate key" error
-			log.Println("failed to create post", err)
+			return database.Feed{}, err
 		}
+		return moved, tx.Commit()
+	}
+	if err != nil {
+		return database.Feed{}, err
 	}
 
-	log.Printf("Feed %s collected, %v posts found", feed.Name, len(rssFeed.Channel.Item))
+	err = qtx.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{
+		ToFeedID:   existing.ID,
+		UpdatedAt:  time.Now().UTC(),
+		FromFeedID: feed.ID,
+	})
+	if err != nil {
+		return database.Feed{}, err
+	}
+	// Users who already followed both keep their folders on the follow that's left
+	err = qtx.MoveFeedFollowFolders(ctx, database.MoveFeedFollowFoldersParams{
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+	if err != nil {
+		return database.Feed{}, err
+	}
+	err = qtx.MovePosts(ctx, database.MovePostsParams{
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+	if err != nil {
+		return database.Feed{}, err
+	}
+	// Posts that didn't move are duplicates, stars and reads go to the copy that stays
+	// Stars aren't deleted with their post, so ones the user has on both copies go first
+	err = qtx.DeleteDuplicatePostStars(ctx, database.DeleteDuplicatePostStarsParams{
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+	if err != nil {
+		return database.Feed{}, err
+	}
+	err = qtx.MovePostStars(ctx, database.MovePostStarsParams{
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+	if err != nil {
+		return database.Feed{}, err
+	}
+	err = qtx.MovePostReads(ctx, database.MovePostReadsParams{
+		ToFeedID:   existing.ID,
+		FromFeedID: feed.ID,
+	})
+	if err != nil {
+		return database.Feed{}, err
+	}
+	// Whatever didn't move is a duplicate, goes with the feed
+	err = qtx.DeleteFeed(ctx, feed.ID)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	return existing, tx.Commit()
+}
+
+// Back off exponentially while a feed keeps failing
+// Disable it once it's failed maxFailures times in a row, 0 means never disable
+func (scr *scraper) recordFetchFailure(ctx context.Context, feed database.Feed, fetchErr error, retryAfter time.Duration) {
+	failed, err := scr.DB.RecordFeedFetchFailure(ctx, database.RecordFeedFetchFailureParams{
+		ID:        feed.ID,
+		LastError: nullString(fetchErr.Error()),
+	})
+	if err != nil {
+		log.Println("Error recording feed fetch failure:", err)
+		return
+	}
+
+	if scr.maxFailures > 0 && int(failed.ConsecutiveFailures) >= scr.maxFailures {
+		log.Printf("Feed %s failed %v times in a row, disabling", feed.Name, failed.ConsecutiveFailures)
+		err = scr.DB.DisableFeed(ctx, database.DisableFeedParams{
+			Now: time.Now().UTC(),
+			ID:  feed.ID,
+		})
+		if err != nil {
+			log.Println("Error disabling feed:", err)
+		}
+		return
+	}
+
+	next := time.Now().UTC().Add(failureBackoff(failed.ConsecutiveFailures, retryAfter))
+	err = scr.DB.SetFeedNextFetchAt(ctx, database.SetFeedNextFetchAtParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: next, Valid: true},
+	})
+	if err != nil {
+		log.Println("Error scheduling next fetch:", err)
+	}
+}
+
+// Set when feed is next due based on how often it publishes and what publisher asked for
+func (scr *scraper) scheduleNextFetch(ctx context.Context, feed database.Feed, hints fetchHints) {
+	postDates, err := scr.DB.GetRecentPostDates(ctx, database.GetRecentPostDatesParams{
+		FeedID: feed.ID,
+		Limit:  postingFrequencySample,
+	})
+	if err != nil {
+		// Still schedule, just without posting frequency
+		log.Println("Error getting recent post dates:", err)
+	}
+
+	next := nextFetchAt(time.Now().UTC(), postDates, hints)
+	err = scr.DB.SetFeedNextFetchAt(ctx, database.SetFeedNextFetchAtParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: next, Valid: true},
+	})
+	if err != nil {
+		log.Println("Error scheduling next fetch:", err)
+	}
+}
+
+// What savePost did with an item
+type postSaveResult int
+
+const (
+	postUnchanged postSaveResult = iota
+	postCreated
+	postUpdated
+)
+
+// Store item as a new post, or update the stored post if publisher changed it
+// Previous version of an updated post is kept in post_revisions
+func (scr *scraper) savePost(ctx context.Context, feed database.Feed, item RSSItem, fetchedAt time.Time) (postSaveResult, error) {
+	// If item description is blank, set the value to null in database
+	description := nullString(item.Description)
+
+	// Never drop a post because of its date
+	// Falls back to updated date and then fetch time, source records which one was used
+	pubAt, pubAtSource := itemPublishedAt(item, fetchedAt)
+	if pubAtSource == publishedAtSourceFetched {
+		log.Printf("couldn't parse any date %q for %v, using fetch time", firstNonEmpty(item.PubDate, item.DCDate, item.Updated), item.Link)
+	}
+
+	// Items without a link but with a permalink GUID still have somewhere to point at
+	link := item.Link
+	if link == "" && isPermalink(item.GUID) {
+		link = item.GUID
+	}
+
+	guid := itemGUID(item)
+	existing, err := scr.DB.GetPostByGUID(ctx, database.GetPostByGUIDParams{
+		FeedID: feed.ID,
+		Guid:   guid,
+	})
+	// Stored before we tracked GUIDs, URL was used as its GUID
+	if errors.Is(err, sql.ErrNoRows) && item.Link != "" && item.Link != guid {
+		existing, err = scr.DB.AdoptLegacyPostGUID(ctx, database.AdoptLegacyPostGUIDParams{
+			Guid:      guid,
+			UpdatedAt: time.Now().UTC(),
+			FeedID:    feed.ID,
+			Url:       item.Link,
+		})
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		// Number of rows inserted, 0 means another scrape stored it in the meantime
+		created, err := scr.DB.CreatePost(ctx, database.CreatePostParams{
+			ID:                uuid.New(),
+			CreatedAt:         time.Now().UTC(),
+			UpdatedAt:         time.Now().UTC(),
+			Title:             item.Title,
+			Description:       description,
+			PublishedAt:       pubAt,
+			Url:               link,
+			FeedID:            feed.ID,
+			PublishedAtSource: pubAtSource,
+			Guid:              guid,
+			ContentHash:       postContentHash(item.Title, description, pubAt, pubAtSource, link),
+		})
+		if err != nil || created == 0 {
+			return postUnchanged, err
+		}
+		return postCreated, nil
+	}
+	if err != nil {
+		return postUnchanged, err
+	}
+
+	// Fetch time changes every scrape, keep the date we stored first time
+	if pubAtSource == publishedAtSourceFetched {
+		pubAt = existing.PublishedAt
+		pubAtSource = existing.PublishedAtSource
+	}
+	contentHash := postContentHash(item.Title, description, pubAt, pubAtSource, link)
+
+	// Posts stored before we hashed content have no hash, work it out from what's stored
+	// Same content just gets its hash filled in, it isn't an update
+	if existing.ContentHash == "" {
+		existing.ContentHash = postContentHash(existing.Title, existing.Description, existing.PublishedAt, existing.PublishedAtSource, existing.Url)
+		if contentHash == existing.ContentHash {
+			err = scr.DB.SetPostContentHash(ctx, database.SetPostContentHashParams{
+				ID:          existing.ID,
+				ContentHash: contentHash,
+			})
+			return postUnchanged, err
+		}
+	}
+	if contentHash == existing.ContentHash {
+		return postUnchanged, nil
+	}
+
+	_, err = scr.DB.CreatePostRevision(ctx, database.CreatePostRevisionParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now().UTC(),
+		PostID:      existing.ID,
+		Title:       existing.Title,
+		Description: existing.Description,
+		PublishedAt: existing.PublishedAt,
+		Url:         existing.Url,
+		ContentHash: existing.ContentHash,
+	})
+	if err != nil {
+		return postUnchanged, err
+	}
+
+	_, err = scr.DB.UpdatePost(ctx, database.UpdatePostParams{
+		ID:                existing.ID,
+		Title:             item.Title,
+		Description:       description,
+		PublishedAt:       pubAt,
+		Url:               link,
+		PublishedAtSource: pubAtSource,
+		ContentHash:       contentHash,
+		UpdatedAt:         time.Now().UTC(),
+	})
+	if err != nil {
+		return postUnchanged, err
+	}
+	return postUpdated, nil
+}
+
+// Fingerprint of the parts of a post publishers change
+// Fetch time isn't part of the content, leave it out so it doesn't look like a change
+func postContentHash(title string, description sql.NullString, pubAt time.Time, pubAtSource string, link string) string {
+	pubDate := ""
+	if pubAtSource != publishedAtSourceFetched {
+		pubDate = pubAt.UTC().Format(time.RFC3339)
+	}
+	hash := sha256.Sum256([]byte(title + "\x00" + description.String + "\x00" + pubDate + "\x00" + link))
+	return hex.EncodeToString(hash[:])
+}
+
+// Identity of an item within its feed
+// Publisher's GUID if it has one, otherwise the link
+// Items with neither get a hash of their content so same item still maps to same post
+func itemGUID(item RSSItem) string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
+	}
+	hash := sha256.Sum256([]byte(item.Title + "\n" + item.PubDate + "\n" + item.Description))
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+// RSS GUIDs are permalinks by default, but plenty of feeds put opaque IDs in there
+func isPermalink(guid string) bool {
+	return strings.HasPrefix(guid, "http://") || strings.HasPrefix(guid, "https://")
+}
+
+// NullString has string itself and whether it's valid
+// Blank string stored as null in database
+func nullString(s string) sql.NullString {
+	return sql.NullString{
+		String: s,
+		Valid:  s != "",
+	}
 }
@@ -1,10 +1,41 @@
 -- name: CreateFeedFollow :one
+-- Already following returns no rows, use GetFeedFollowForFeed to get the existing follow
 INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
 VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, feed_id) DO NOTHING
 RETURNING *;
 
+-- name: GetFeedFollowForFeed :one
+SELECT * FROM feed_follows WHERE user_id = $1 AND feed_id = $2;
+
 -- name: GetFeedFollows :many
-SELECT * FROM feed_follows WHERE user_id = $1;
+SELECT sqlc.embed(feed_follows),
+-- Folders the follow is filed in
+ARRAY(
+    SELECT feed_follow_folders.folder_id FROM feed_follow_folders
+    WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+)::uuid[] AS folder_ids,
+-- Posts in the feed this user hasn't read yet
+(
+    SELECT COUNT(*) FROM posts
+    WHERE posts.feed_id = feed_follows.feed_id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+    )
+) AS unread_count
+FROM feed_follows WHERE user_id = $1;
+
+-- name: GetFollowedFeedsForExport :many
+-- Everything an OPML export needs, grouped by folder
+-- A follow in several folders comes back once for each, unfiled ones have no folder name
+SELECT feeds.name, feeds.url, folders.name AS folder_name
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+LEFT JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+LEFT JOIN folders ON folders.id = feed_follow_folders.folder_id
+WHERE feed_follows.user_id = $1
+ORDER BY folders.position NULLS FIRST, folders.name, lower(feeds.name);
 
 -- name: DeleteFeedFollow :exec
 -- Not returning record, just run a SQL query
@@ -12,3 +43,13 @@ SELECT * FROM feed_follows WHERE user_id = $1;
 -- Tacking on user_id is prevent someone who doesn't own FeedFollow to unfollow
 -- Ensures only user who owns follow record can unfollow
 DELETE FROM feed_follows WHERE id = $1 AND user_id = $2;
+
+-- name: MoveFeedFollows :exec
+-- Merging feeds, move follows from one feed to another
+-- Users already following the other feed keep their existing follow
+UPDATE feed_follows
+SET feed_id = sqlc.arg(to_feed_id), updated_at = sqlc.arg(updated_at)
+WHERE feed_id = sqlc.arg(from_feed_id)
+AND user_id NOT IN (
+    SELECT user_id FROM feed_follows WHERE feed_id = sqlc.arg(to_feed_id)
+);
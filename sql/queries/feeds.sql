@@ -1,6 +1,6 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, description, site_url, language, image_url, generator)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING *;
 
 -- name: GetFeeds :many
@@ -8,19 +8,121 @@ RETURNING *;
 -- Not an authenticated endpoint
 SELECT * FROM feeds;
 
--- name: GetNextFeedsToFetch :many
--- Purpose is to get feed that next needs to be fetched
+-- name: ClaimFeedsToFetch :many
+-- Purpose is to get feeds that next need to be fetched and claim them in one statement
 -- Find any feeds that have never been fetched before, priority
 -- If every feed fetched, find fetched longest ago
--- Many goroutines to fetch different feeds
-SELECT * FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
--- Pass in how many feeds we want
-LIMIT $1;
-
--- name: MarkFeedAsFetched :one
+-- Many goroutines, and many server instances, fetch different feeds
 UPDATE feeds
 -- For auditing purposes
-SET last_fetched_at = NOW(), updated_at = NOW()
+-- Lease stops other instances claiming feed while we fetch it
+-- now comes from Go, next_fetch_at and lease_expires_at are UTC from Go too
+-- NOW() would be in the session's time zone and not line up with them
+SET last_fetched_at = sqlc.arg(now)::timestamp, updated_at = sqlc.arg(now)::timestamp, lease_expires_at = sqlc.arg(lease_expires_at)
+WHERE id IN (
+    SELECT id FROM feeds
+    -- Only feeds that are due, never scheduled means due now
+    -- Disabled feeds failed too many times, stop fetching them
+    WHERE (next_fetch_at IS NULL OR next_fetch_at <= sqlc.arg(now)::timestamp) AND disabled_at IS NULL
+    -- Nobody else is fetching it, or whoever was crashed and lease ran out
+    AND (lease_expires_at IS NULL OR lease_expires_at <= sqlc.arg(now)::timestamp)
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    -- Pass in how many feeds we want
+    LIMIT sqlc.arg(limit)
+    -- Rows another instance is claiming right now are skipped instead of waited on
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ReleaseFeedLease :exec
+-- Done fetching, feed can be claimed again once it's due
+UPDATE feeds
+SET lease_expires_at = NULL
+WHERE id = $1;
+
+-- name: UpdateFeedCacheHeaders :exec
+-- Store ETag and Last-Modified so next fetch can be conditional
+UPDATE feeds
+SET etag = $2, last_modified = $3, updated_at = $4
+WHERE id = $1;
+
+
+-- name: UpdateFeedFetchHints :exec
+-- ttl, skipHours and skipDays from the last full fetch, a 304 has no body to read them from
+UPDATE feeds
+SET ttl_minutes = $2, skip_hours = $3, skip_days = $4
+WHERE id = $1;
+
+-- name: UpdateFeedMetadata :exec
+-- What the feed says about itself, kept up to date on every fetch
+UPDATE feeds
+SET description = $2, site_url = $3, language = $4, image_url = $5, generator = $6
+WHERE id = $1;
+
+-- name: SetFeedNextFetchAt :exec
+-- Schedule next fetch once we know how often feed changes
+UPDATE feeds
+SET next_fetch_at = $2
+WHERE id = $1;
+
+-- name: RecordFeedFetchSuccess :exec
+-- Feed is healthy again, reset failure tracking
+-- Re-enables a disabled feed that was fetched on demand and works again
+-- now comes from Go like every other timestamp we show
+UPDATE feeds
+SET last_success_at = sqlc.arg(now)::timestamp, consecutive_failures = 0, last_error = NULL, disabled_at = NULL
+WHERE id = sqlc.arg(id);
+
+-- name: RecordFeedFetchFailure :one
+-- Returns updated feed so caller knows how many times in a row it's failed
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1, last_error = $2
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: DisableFeed :exec
+UPDATE feeds
+SET disabled_at = sqlc.arg(now)::timestamp, updated_at = sqlc.arg(now)::timestamp
+WHERE id = sqlc.arg(id);
+
+-- name: GetFeedByURL :one
+SELECT * FROM feeds WHERE url = $1;
+
+-- name: UpdateFeedURL :one
+-- Feed permanently moved, remember new URL
+UPDATE feeds
+SET url = $2, updated_at = $3
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteFeed :exec
+-- Follows and posts are deleted with it, ON DELETE CASCADE
+-- Stars aren't, they keep a copy of their post
+DELETE FROM feeds WHERE id = $1;
+
+-- name: ClaimFeed :one
+-- Claim one specific feed, whether it's due or not
+-- Used when fetching a feed on demand, still respects other instances' leases
+-- now comes from Go, same as in ClaimFeedsToFetch
+UPDATE feeds
+SET last_fetched_at = sqlc.arg(now)::timestamp, updated_at = sqlc.arg(now)::timestamp, lease_expires_at = sqlc.arg(lease_expires_at)
+WHERE id = sqlc.arg(id) AND (lease_expires_at IS NULL OR lease_expires_at <= sqlc.arg(now)::timestamp)
+RETURNING *;
+
+-- name: GetFeed :one
+SELECT * FROM feeds WHERE id = $1;
+
+-- name: UpdateFeed :one
+-- Changing URL starts fetch state over, it's fetched again on next round
+UPDATE feeds
+SET name = sqlc.arg(name),
+    etag = CASE WHEN url = sqlc.arg(url) THEN etag END,
+    last_modified = CASE WHEN url = sqlc.arg(url) THEN last_modified END,
+    next_fetch_at = CASE WHEN url = sqlc.arg(url) THEN next_fetch_at END,
+    last_error = CASE WHEN url = sqlc.arg(url) THEN last_error END,
+    consecutive_failures = CASE WHEN url = sqlc.arg(url) THEN consecutive_failures ELSE 0 END,
+    disabled_at = CASE WHEN url = sqlc.arg(url) THEN disabled_at END,
+    url = sqlc.arg(url),
+    updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,87 @@
+-- name: CreateFolder :one
+-- New folders go at the end
+INSERT INTO folders (id, created_at, updated_at, user_id, name, position)
+VALUES (
+    $1, $2, $3, $4, $5,
+    (SELECT COALESCE(MAX(position) + 1, 0) FROM folders WHERE user_id = $4)
+)
+RETURNING *;
+
+-- name: UpsertFolder :one
+-- Folder with this name, created if the user doesn't have one yet
+INSERT INTO folders (id, created_at, updated_at, user_id, name, position)
+VALUES (
+    $1, $2, $3, $4, $5,
+    (SELECT COALESCE(MAX(position) + 1, 0) FROM folders WHERE user_id = $4)
+)
+-- No-op update so existing folder is returned
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: GetFoldersForUser :many
+SELECT sqlc.embed(folders),
+-- Unread posts across all feeds in the folder
+(
+    SELECT COUNT(*) FROM posts
+    JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+    JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+    WHERE feed_follow_folders.folder_id = folders.id
+    AND NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = folders.user_id
+    )
+) AS unread_count
+FROM folders
+WHERE user_id = $1
+ORDER BY position, name;
+
+-- name: RenameFolder :one
+UPDATE folders
+SET name = $3, updated_at = $4
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: ReorderFolders :execrows
+-- Position of each folder is its index in folder_ids
+UPDATE folders
+SET position = array_position(sqlc.arg(folder_ids)::uuid[], id) - 1, updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(user_id) AND id = ANY(sqlc.arg(folder_ids)::uuid[]);
+
+-- name: DeleteFolder :execrows
+-- Follows in it are only unfiled, not unfollowed
+DELETE FROM folders WHERE id = $1 AND user_id = $2;
+
+-- name: AddFeedFollowToFolder :execrows
+-- Both follow and folder have to belong to the user
+-- Already in the folder does nothing
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT feed_follows.id, folders.id FROM feed_follows, folders
+WHERE feed_follows.id = sqlc.arg(feed_follow_id) AND feed_follows.user_id = sqlc.arg(user_id)
+AND folders.id = sqlc.arg(folder_id) AND folders.user_id = sqlc.arg(user_id)
+ON CONFLICT (feed_follow_id, folder_id) DO NOTHING;
+
+-- name: AddFollowedFeedToFolder :exec
+-- Same as AddFeedFollowToFolder for when we know the feed rather than the follow
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT feed_follows.id, folders.id FROM feed_follows, folders
+WHERE feed_follows.feed_id = sqlc.arg(feed_id) AND feed_follows.user_id = sqlc.arg(user_id)
+AND folders.id = sqlc.arg(folder_id) AND folders.user_id = sqlc.arg(user_id)
+ON CONFLICT (feed_follow_id, folder_id) DO NOTHING;
+
+-- name: RemoveFeedFollowFromFolder :execrows
+DELETE FROM feed_follow_folders
+USING folders
+WHERE feed_follow_folders.folder_id = folders.id
+AND feed_follow_folders.feed_follow_id = sqlc.arg(feed_follow_id)
+AND folders.id = sqlc.arg(folder_id) AND folders.user_id = sqlc.arg(user_id);
+
+-- name: MoveFeedFollowFolders :exec
+-- Merging feeds, follows left behind belong to users already following the other feed
+-- Their folders carry over to the follow they keep
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT to_follows.id, feed_follow_folders.folder_id
+FROM feed_follow_folders
+JOIN feed_follows AS from_follows ON from_follows.id = feed_follow_folders.feed_follow_id
+JOIN feed_follows AS to_follows ON to_follows.user_id = from_follows.user_id
+WHERE to_follows.feed_id = sqlc.arg(to_feed_id) AND from_follows.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (feed_follow_id, folder_id) DO NOTHING;
@@ -0,0 +1,52 @@
+-- name: MarkPostsRead :execrows
+-- Only posts from feeds the user follows can be marked
+-- Already read posts keep when they were first read
+-- read_at is UTC from Go, same as the post timestamps it's shown with
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)::timestamp FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts.id = ANY(sqlc.arg(post_ids)::uuid[])
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostsUnread :execrows
+DELETE FROM post_reads
+WHERE user_id = sqlc.arg(user_id) AND post_id = ANY(sqlc.arg(post_ids)::uuid[]);
+
+-- name: MarkAllPostsRead :execrows
+-- Everything published up to a point, in one feed or all followed feeds
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)::timestamp FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts.published_at <= sqlc.arg(up_to)
+AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: GetUnreadCountsForUser :many
+-- Number of unread posts in every feed the user follows, 0 included
+SELECT feed_follows.feed_id, COUNT(posts.id) AS unread_count
+FROM feed_follows
+LEFT JOIN posts ON posts.feed_id = feed_follows.feed_id
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+)
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+-- Optionally only feeds in one folder
+AND (sqlc.narg(folder_id)::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_folders
+    WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+    AND feed_follow_folders.folder_id = sqlc.narg(folder_id)
+))
+GROUP BY feed_follows.feed_id;
+
+-- name: MovePostReads :exec
+-- Merging feeds, read state on posts left behind goes to the same post in the other feed
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT post_reads.user_id, to_posts.id, post_reads.read_at
+FROM post_reads
+JOIN posts AS from_posts ON from_posts.id = post_reads.post_id
+JOIN posts AS to_posts ON to_posts.guid = from_posts.guid
+WHERE to_posts.feed_id = sqlc.arg(to_feed_id) AND from_posts.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (user_id, post_id) DO NOTHING;
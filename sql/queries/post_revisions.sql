@@ -0,0 +1,15 @@
+-- name: CreatePostRevision :one
+INSERT INTO post_revisions (
+    id, created_at, post_id, title, description, published_at, url, content_hash
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetPostRevisionsForUser :many
+SELECT post_revisions.* FROM post_revisions
+JOIN posts ON post_revisions.post_id = posts.id
+-- Only let users see history of posts from feeds they follow
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE post_revisions.post_id = $1 AND feed_follows.user_id = $2
+-- Most recent version first
+ORDER BY post_revisions.created_at DESC;
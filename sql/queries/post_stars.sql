@@ -0,0 +1,83 @@
+-- name: StarPost :one
+-- Can star posts from feeds the user follows, or update a star they already have
+-- Starring again replaces note and labels
+-- Post is copied onto the star so it can still be shown once the post is deleted
+-- now is UTC from Go, starred posts are paged by it alongside Go-written post timestamps
+INSERT INTO post_stars (
+    user_id, post_id, created_at, updated_at, note, labels,
+    feed_id, title, description, url, published_at, published_at_source, guid
+)
+SELECT sqlc.arg(user_id)::uuid, posts.id, sqlc.arg(now)::timestamp, sqlc.arg(now)::timestamp, sqlc.narg(note)::text, sqlc.arg(labels)::text[],
+    posts.feed_id, posts.title, posts.description, posts.url, posts.published_at, posts.published_at_source, posts.guid
+FROM posts
+WHERE posts.id = sqlc.arg(post_id)
+AND (
+    EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+    )
+    OR EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.post_id = posts.id AND post_stars.user_id = sqlc.arg(user_id)
+    )
+)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET note = EXCLUDED.note, labels = EXCLUDED.labels, updated_at = EXCLUDED.updated_at,
+    -- Post may have changed since it was starred
+    feed_id = EXCLUDED.feed_id, title = EXCLUDED.title, description = EXCLUDED.description, url = EXCLUDED.url,
+    published_at = EXCLUDED.published_at, published_at_source = EXCLUDED.published_at_source, guid = EXCLUDED.guid
+RETURNING *;
+
+-- name: UnstarPost :execrows
+DELETE FROM post_stars WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetStarredPostsForUser :many
+-- Starred posts stay listed even after unfollowing their feed, or after the post is deleted
+-- Deleted posts are shown from the copy kept on the star
+SELECT sqlc.embed(post_stars),
+coalesce(posts.title, post_stars.title) AS title,
+coalesce(posts.description, post_stars.description) AS description,
+coalesce(posts.url, post_stars.url) AS url,
+coalesce(posts.published_at, post_stars.published_at) AS published_at,
+coalesce(posts.published_at_source, post_stars.published_at_source) AS published_at_source,
+coalesce(posts.feed_id, post_stars.feed_id) AS feed_id,
+coalesce(posts.guid, post_stars.guid) AS guid,
+-- Deleted posts use when they were starred
+coalesce(posts.created_at, post_stars.created_at) AS post_created_at,
+coalesce(posts.updated_at, post_stars.updated_at) AS post_updated_at,
+posts.id IS NULL AS post_deleted,
+EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = post_stars.post_id AND post_reads.user_id = post_stars.user_id
+) AS read
+FROM post_stars
+LEFT JOIN posts ON posts.id = post_stars.post_id
+WHERE post_stars.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(label)::text IS NULL OR sqlc.narg(label) = ANY(post_stars.labels))
+-- Cursor is the last star of previous page
+AND (
+    sqlc.narg(cursor_starred_at)::timestamp IS NULL
+    OR (post_stars.created_at, post_stars.post_id) < (sqlc.narg(cursor_starred_at), sqlc.narg(cursor_id)::uuid)
+)
+-- Most recently starred first
+ORDER BY post_stars.created_at DESC, post_stars.post_id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: DeleteDuplicatePostStars :exec
+-- Merging feeds, user starred the same post in both feeds
+-- Star in the other feed is kept, run before MovePostStars
+DELETE FROM post_stars
+USING posts AS from_posts, posts AS to_posts, post_stars AS kept
+WHERE post_stars.post_id = from_posts.id
+AND to_posts.guid = from_posts.guid
+AND kept.user_id = post_stars.user_id AND kept.post_id = to_posts.id
+AND to_posts.feed_id = sqlc.arg(to_feed_id) AND from_posts.feed_id = sqlc.arg(from_feed_id);
+
+-- name: MovePostStars :exec
+-- Merging feeds, stars on posts left behind go to the same post in the other feed
+UPDATE post_stars
+SET post_id = to_posts.id, feed_id = to_posts.feed_id
+FROM posts AS from_posts, posts AS to_posts
+WHERE post_stars.post_id = from_posts.id
+AND to_posts.guid = from_posts.guid
+AND to_posts.feed_id = sqlc.arg(to_feed_id) AND from_posts.feed_id = sqlc.arg(from_feed_id);
@@ -1,16 +1,139 @@
--- name: CreatePost :one
+-- name: CreatePost :execrows
+-- Returns number of rows inserted, 0 means already have this post
 INSERT INTO posts (
-    id, created_at, updated_at, title, description, published_at, url, feed_id
+    id, created_at, updated_at, title, description, published_at, url, feed_id,
+    published_at_source, guid, content_hash
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING *;
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+-- Same item from same feed, nothing to do
+ON CONFLICT (feed_id, guid) DO NOTHING;
 
 -- name: GetPostsForUser :many
-SELECT posts.* FROM posts
+SELECT sqlc.embed(posts),
+-- Whether this user has read the post
+EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+) AS read,
+-- Whether this user starred the post
+EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id AND post_stars.user_id = feed_follows.user_id
+) AS starred
+FROM posts
 -- Know what feed every post in the database belongs to
 JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
 -- Tells us which feeds User is following
-WHERE feed_follows.user_id = $1
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+-- Optional filters, null means don't filter
+AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+AND (sqlc.narg(folder_id)::uuid IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_folders
+    WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+    AND feed_follow_folders.folder_id = sqlc.narg(folder_id)
+))
+AND (sqlc.narg(since)::timestamp IS NULL OR posts.published_at >= sqlc.narg(since))
+AND (sqlc.narg(until)::timestamp IS NULL OR posts.published_at < sqlc.narg(until))
+AND (NOT sqlc.arg(unread_only)::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+))
+-- Cursor is the last post of previous page, carry on after it
+-- id breaks ties between posts published at the same time
+AND (
+    sqlc.narg(cursor_published_at)::timestamp IS NULL
+    OR (posts.published_at, posts.id) < (sqlc.narg(cursor_published_at), sqlc.narg(cursor_id)::uuid)
+)
 -- Newest stuff first
-ORDER BY posts.published_at DESC
-LIMIT $2;
\ No newline at end of file
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: GetPostByGUID :one
+-- Post already stored for an item, if there is one
+SELECT * FROM posts WHERE feed_id = $1 AND guid = $2;
+
+-- name: AdoptLegacyPostGUID :one
+-- Posts stored before GUIDs were tracked have their URL as GUID
+-- Give the one with the item's link the item's real GUID instead of storing the item again
+UPDATE posts
+SET guid = sqlc.arg(guid), updated_at = sqlc.arg(updated_at)
+WHERE feed_id = sqlc.arg(feed_id) AND guid = url AND url = sqlc.arg(url)
+RETURNING *;
+
+-- name: UpdatePost :one
+-- Publisher changed the item, overwrite with latest version
+UPDATE posts
+SET title = $2, description = $3, published_at = $4, url = $5,
+    published_at_source = $6, content_hash = $7, updated_at = $8
+WHERE id = $1
+RETURNING *;
+
+-- name: SetPostContentHash :exec
+-- Posts stored before content was hashed, nothing changed so updated_at stays
+UPDATE posts
+SET content_hash = $2
+WHERE id = $1;
+
+-- name: GetRecentPostDates :many
+-- Used to work out how often a feed publishes
+-- Fetch time fallbacks say nothing about publishing frequency
+SELECT published_at FROM posts
+WHERE feed_id = $1 AND published_at_source <> 'fetched'
+ORDER BY published_at DESC
+LIMIT $2;
+
+-- name: MovePosts :exec
+-- Merging feeds, move posts from one feed to another
+-- Posts the other feed already has are left behind
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id)
+AND guid NOT IN (
+    SELECT guid FROM posts WHERE feed_id = sqlc.arg(to_feed_id)
+);
+
+-- name: SearchPostsForUser :many
+-- Full-text search over posts in feeds the user follows, best matches first
+-- websearch_to_tsquery understands "quoted phrases", or and -excluded words
+SELECT sqlc.embed(posts),
+EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+) AS read,
+EXISTS (
+    SELECT 1 FROM post_stars
+    WHERE post_stars.post_id = posts.id AND post_stars.user_id = feed_follows.user_id
+) AS starred,
+ts_rank(
+    posts_search_vector(posts.title, posts.description),
+    websearch_to_tsquery('english', sqlc.arg(query))
+)::real AS rank,
+-- Matching words wrapped in <mark>, rest of the text is HTML-escaped so it's safe to render
+ts_headline(
+    'english', html_escape(posts.title), websearch_to_tsquery('english', sqlc.arg(query)),
+    'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'
+)::text AS title_highlight,
+-- Tags stripped from description first so snippets don't cut them in half
+ts_headline(
+    'english', html_escape(regexp_replace(coalesce(posts.description, ''), '<[^>]*>', ' ', 'g')),
+    websearch_to_tsquery('english', sqlc.arg(query)),
+    'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10'
+)::text AS snippet
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts_search_vector(posts.title, posts.description) @@ websearch_to_tsquery('english', sqlc.arg(query))
+AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+-- Cursor is the last result of previous page
+AND (
+    sqlc.narg(cursor_rank)::real IS NULL
+    OR (
+        ts_rank(
+            posts_search_vector(posts.title, posts.description),
+            websearch_to_tsquery('english', sqlc.arg(query))
+        )::real,
+        posts.id
+    ) < (sqlc.narg(cursor_rank), sqlc.narg(cursor_id)::uuid)
+)
+ORDER BY rank DESC, posts.id DESC
+LIMIT sqlc.arg(page_size);
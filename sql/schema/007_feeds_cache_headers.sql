@@ -0,0 +1,9 @@
+-- +goose Up
+-- Validators from last response, sent back on next fetch so publisher can answer 304 Not Modified
+-- Nullable, not every server sends them
+ALTER TABLE feeds ADD COLUMN etag TEXT;
+ALTER TABLE feeds ADD COLUMN last_modified TEXT;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN last_modified;
+ALTER TABLE feeds DROP COLUMN etag;
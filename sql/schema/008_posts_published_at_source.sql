@@ -0,0 +1,7 @@
+-- +goose Up
+-- Which date published_at came from: published, dc_date, updated or fetched
+-- Lets us tell real publication dates apart from fallbacks instead of dropping the post
+ALTER TABLE posts ADD COLUMN published_at_source TEXT NOT NULL DEFAULT 'published';
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN published_at_source;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Identity of a post is the item's GUID within its feed, not its URL
+-- Links change (tracking params), some items have no link, and two feeds can link the same article
+ALTER TABLE posts ADD COLUMN guid TEXT;
+-- Existing posts were identified by URL, keep using it as their GUID
+UPDATE posts SET guid = url;
+ALTER TABLE posts ALTER COLUMN guid SET NOT NULL;
+
+ALTER TABLE posts DROP CONSTRAINT posts_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_feed_id_guid_key UNIQUE (feed_id, guid);
+
+-- +goose Down
+ALTER TABLE posts DROP CONSTRAINT posts_feed_id_guid_key;
+-- Since Up, the same URL can be in several feeds or on several items of one feed
+-- URL has to be unique again, keep the first post stored with each URL and delete the rest
+-- Reads, stars and revisions of the deleted copies go with them
+DELETE FROM posts
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY url ORDER BY created_at, id) AS position
+        FROM posts
+    ) numbered
+    WHERE position > 1
+);
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
+ALTER TABLE posts DROP COLUMN guid;
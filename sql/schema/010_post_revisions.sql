@@ -0,0 +1,21 @@
+-- +goose Up
+-- Hash of title, description, published date and link
+-- Lets scraper tell if publisher changed an item since we stored it
+ALTER TABLE posts ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+
+-- Previous versions of a post, saved every time scraper updates it
+CREATE TABLE post_revisions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    -- Post gets deleted, its history goes with it
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    url TEXT NOT NULL,
+    content_hash TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE post_revisions;
+ALTER TABLE posts DROP COLUMN content_hash;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- When feed is next due to be fetched, worked out from how often it publishes
+-- Nullable, null means due now
+ALTER TABLE feeds ADD COLUMN next_fetch_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN next_fetch_at;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Set while a scraper instance is fetching the feed so other instances skip it
+-- If instance crashes lease runs out and feed can be claimed again
+ALTER TABLE feeds ADD COLUMN lease_expires_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN lease_expires_at;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Posts are listed newest first per feed and paged by (published_at, id)
+CREATE INDEX posts_feed_id_published_at_id_idx ON posts (feed_id, published_at DESC, id DESC);
+
+-- +goose Down
+DROP INDEX posts_feed_id_published_at_id_idx;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Posts a user has read, no row means unread
+-- Read state is per user, same post can be read by one follower and unread by another
+CREATE TABLE post_reads (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    read_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+-- Deleting posts looks rows up by post
+CREATE INDEX post_reads_post_id_idx ON post_reads (post_id);
+
+-- +goose Down
+DROP TABLE post_reads;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Posts a user saved, with an optional note and their own labels
+CREATE TABLE post_stars (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    note TEXT,
+    labels TEXT[] NOT NULL DEFAULT '{}',
+    PRIMARY KEY (user_id, post_id)
+);
+-- Starred posts are listed most recently starred first
+CREATE INDEX post_stars_user_id_created_at_idx ON post_stars (user_id, created_at DESC, post_id DESC);
+CREATE INDEX post_stars_post_id_idx ON post_stars (post_id);
+
+-- +goose Down
+DROP TABLE post_stars;
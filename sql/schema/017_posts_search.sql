@@ -0,0 +1,15 @@
+-- +goose Up
+-- What full-text search matches against, title counts for more than description
+-- A function so the index and the queries are sure to use the same expression
+-- +goose StatementBegin
+CREATE FUNCTION posts_search_vector(title TEXT, description TEXT) RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('english', coalesce(title, '')), 'A')
+        || setweight(to_tsvector('english', coalesce(description, '')), 'B')
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+CREATE INDEX posts_search_idx ON posts USING GIN (posts_search_vector(title, description));
+
+-- +goose Down
+DROP INDEX posts_search_idx;
+DROP FUNCTION posts_search_vector;
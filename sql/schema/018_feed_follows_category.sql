@@ -0,0 +1,6 @@
+-- +goose Up
+-- Folder a follow was filed under in the reader it was imported from, null when not in one
+ALTER TABLE feed_follows ADD COLUMN category TEXT;
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN category;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- Named folders a user files their follows into, e.g. "Security" or "Go blogs"
+CREATE TABLE folders (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    -- Order folders are shown in, lowest first
+    position INTEGER NOT NULL DEFAULT 0,
+    UNIQUE(user_id, name)
+);
+
+-- A follow can be in any number of folders, or none
+CREATE TABLE feed_follow_folders (
+    feed_follow_id UUID NOT NULL REFERENCES feed_follows(id) ON DELETE CASCADE,
+    -- Deleting a folder unfiles its follows, doesn't unfollow
+    folder_id UUID NOT NULL REFERENCES folders(id) ON DELETE CASCADE,
+    PRIMARY KEY (feed_follow_id, folder_id)
+);
+CREATE INDEX feed_follow_folders_folder_id_idx ON feed_follow_folders (folder_id);
+
+-- Categories from OPML imports become folders
+INSERT INTO folders (id, created_at, updated_at, user_id, name, position)
+SELECT gen_random_uuid(), NOW(), NOW(), user_id, category,
+    ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY category) - 1
+FROM (SELECT DISTINCT user_id, category FROM feed_follows WHERE category IS NOT NULL) AS categories;
+
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT feed_follows.id, folders.id FROM feed_follows
+JOIN folders ON folders.user_id = feed_follows.user_id AND folders.name = feed_follows.category;
+
+ALTER TABLE feed_follows DROP COLUMN category;
+
+-- +goose Down
+ALTER TABLE feed_follows ADD COLUMN category TEXT;
+-- Only one category per follow, keep the first folder
+UPDATE feed_follows SET category = (
+    SELECT folders.name FROM feed_follow_folders
+    JOIN folders ON folders.id = feed_follow_folders.folder_id
+    WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+    ORDER BY folders.position, folders.name
+    LIMIT 1
+);
+DROP TABLE feed_follow_folders;
+DROP TABLE folders;
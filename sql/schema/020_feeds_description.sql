@@ -0,0 +1,6 @@
+-- +goose Up
+-- From the feed itself, null when it doesn't have one
+ALTER TABLE feeds ADD COLUMN description TEXT;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN description;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- About the feed, from the feed itself, updated every time it's fetched
+-- Null when feed doesn't say
+ALTER TABLE feeds ADD COLUMN site_url TEXT;
+ALTER TABLE feeds ADD COLUMN language TEXT;
+ALTER TABLE feeds ADD COLUMN image_url TEXT;
+ALTER TABLE feeds ADD COLUMN generator TEXT;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN generator;
+ALTER TABLE feeds DROP COLUMN image_url;
+ALTER TABLE feeds DROP COLUMN language;
+ALTER TABLE feeds DROP COLUMN site_url;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Search highlights wrap matches in <mark>, so the text around them has to be escaped
+-- or whatever HTML a publisher put in titles and descriptions gets rendered too
+-- & goes first so the other replacements aren't escaped twice
+-- +goose StatementBegin
+CREATE FUNCTION html_escape(value TEXT) RETURNS TEXT AS $$
+    SELECT replace(replace(replace(replace(replace(
+        value, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')
+$$ LANGUAGE SQL IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION html_escape;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- Stars outlive their post, deleting a feed takes its posts but not what users saved
+-- Post as it was when starred is kept on the star so there's still something to show
+-- post_id stays as it was so the star can still be unstarred by it
+ALTER TABLE post_stars DROP CONSTRAINT post_stars_post_id_fkey;
+ALTER TABLE post_stars
+    ADD COLUMN feed_id UUID,
+    ADD COLUMN title TEXT,
+    ADD COLUMN description TEXT,
+    ADD COLUMN url TEXT,
+    ADD COLUMN published_at TIMESTAMP,
+    ADD COLUMN published_at_source TEXT,
+    ADD COLUMN guid TEXT;
+
+UPDATE post_stars
+SET feed_id = posts.feed_id,
+    title = posts.title,
+    description = posts.description,
+    url = posts.url,
+    published_at = posts.published_at,
+    published_at_source = posts.published_at_source,
+    guid = posts.guid
+FROM posts
+WHERE posts.id = post_stars.post_id;
+
+ALTER TABLE post_stars
+    ALTER COLUMN feed_id SET NOT NULL,
+    ALTER COLUMN title SET NOT NULL,
+    ALTER COLUMN url SET NOT NULL,
+    ALTER COLUMN published_at SET NOT NULL,
+    ALTER COLUMN published_at_source SET NOT NULL,
+    ALTER COLUMN guid SET NOT NULL;
+
+-- +goose Down
+-- Stars whose post is gone can't point at it anymore
+DELETE FROM post_stars WHERE post_id NOT IN (SELECT id FROM posts);
+ALTER TABLE post_stars
+    DROP COLUMN feed_id,
+    DROP COLUMN title,
+    DROP COLUMN description,
+    DROP COLUMN url,
+    DROP COLUMN published_at,
+    DROP COLUMN published_at_source,
+    DROP COLUMN guid;
+ALTER TABLE post_stars
+    ADD CONSTRAINT post_stars_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE;
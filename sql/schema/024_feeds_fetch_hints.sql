@@ -0,0 +1,12 @@
+-- +goose Up
+-- ttl, skipHours and skipDays from the last full fetch
+-- A 304 has no body to read them from, so they're kept to schedule from
+-- Days are 0 for Sunday to 6 for Saturday
+ALTER TABLE feeds ADD COLUMN ttl_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN skip_hours INTEGER[] NOT NULL DEFAULT '{}';
+ALTER TABLE feeds ADD COLUMN skip_days INTEGER[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN skip_days;
+ALTER TABLE feeds DROP COLUMN skip_hours;
+ALTER TABLE feeds DROP COLUMN ttl_minutes;